@@ -0,0 +1,194 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Count rows matched by filter
+func Count(ds DSLer, m Cruder, filter Filter) (int64, error) {
+	return CountContext(context.Background(), WithContext(ds), m, filter)
+}
+
+// Count rows matched by filter with context
+func CountContext(ctx context.Context, ds DSLerContext, m Cruder, filter Filter) (count int64, err error) {
+	if err = checkSearch(m, filter); err != nil {
+		return
+	}
+	query, args := aggregateQuery(m, "COUNT(*)", filter)
+	err = dbError(queryRowContext(withOperation(ctx, OpSearch, m), ds, query, args...).Scan(&count))
+	return
+}
+
+// Check if any row matches filter
+func Exists(ds DSLer, m Cruder, filter Filter) (bool, error) {
+	return ExistsContext(context.Background(), WithContext(ds), m, filter)
+}
+
+// Check if any row matches filter with context
+func ExistsContext(ctx context.Context, ds DSLerContext, m Cruder, filter Filter) (exists bool, err error) {
+	if err = checkSearch(m, filter); err != nil {
+		return
+	}
+	query := "SELECT EXISTS (" + SearchQuery(m, filter) + ")"
+	err = dbError(queryRowContext(withOperation(ctx, OpSearch, m), ds, query, Arguments(filter)...).Scan(&exists))
+	return
+}
+
+// Sum of column over rows matched by filter, zero if there are no rows
+func Sum[V any](ds DSLer, m Cruder, column string, filter Filter) (V, error) {
+	return aggregate[V](context.Background(), WithContext(ds), m, "SUM", column, filter)
+}
+
+// Sum of column with context
+func SumContext[V any](ctx context.Context, ds DSLerContext, m Cruder, column string, filter Filter) (V, error) {
+	return aggregate[V](ctx, ds, m, "SUM", column, filter)
+}
+
+// Minimum of column over rows matched by filter, zero if there are no rows
+func Min[V any](ds DSLer, m Cruder, column string, filter Filter) (V, error) {
+	return aggregate[V](context.Background(), WithContext(ds), m, "MIN", column, filter)
+}
+
+// Minimum of column with context
+func MinContext[V any](ctx context.Context, ds DSLerContext, m Cruder, column string, filter Filter) (V, error) {
+	return aggregate[V](ctx, ds, m, "MIN", column, filter)
+}
+
+// Maximum of column over rows matched by filter, zero if there are no rows
+func Max[V any](ds DSLer, m Cruder, column string, filter Filter) (V, error) {
+	return aggregate[V](context.Background(), WithContext(ds), m, "MAX", column, filter)
+}
+
+// Maximum of column with context
+func MaxContext[V any](ctx context.Context, ds DSLerContext, m Cruder, column string, filter Filter) (V, error) {
+	return aggregate[V](ctx, ds, m, "MAX", column, filter)
+}
+
+// Average of column over rows matched by filter, zero if there are no rows
+func Avg[V any](ds DSLer, m Cruder, column string, filter Filter) (V, error) {
+	return aggregate[V](context.Background(), WithContext(ds), m, "AVG", column, filter)
+}
+
+// Average of column with context
+func AvgContext[V any](ctx context.Context, ds DSLerContext, m Cruder, column string, filter Filter) (V, error) {
+	return aggregate[V](ctx, ds, m, "AVG", column, filter)
+}
+
+func aggregate[V any](ctx context.Context, ds DSLerContext, m Cruder, function string, column string, filter Filter) (value V, err error) {
+	if err = checkSearch(m, filter); err != nil {
+		return
+	}
+	if err = checkIdentifier(column); err != nil {
+		return
+	}
+	query, args := aggregateQuery(m, function+"("+quoteIdent(column)+")", filter)
+	var result *V
+	if err = dbError(queryRowContext(withOperation(ctx, OpSearch, m), ds, query, args...).Scan(&result)); err != nil {
+		return
+	}
+	if result != nil {
+		value = *result
+	}
+	return
+}
+
+// Group rows matched by filter and scan group columns followed by aggregates
+// into exported fields of R in order of declaration. Group columns are names,
+// aggregates are SQL expressions, e.g.
+// GroupBy[struct{ Status string; Total int64 }](ds, m, filter, []string{"status"}, "COUNT(*)")
+func GroupBy[R any](ds DSLer, m Cruder, filter Filter, group []string, aggregates ...string) ([]R, error) {
+	return GroupByContext[R](context.Background(), WithContext(ds), m, filter, group, aggregates...)
+}
+
+// Group rows matched by filter with context
+func GroupByContext[R any](ctx context.Context, ds DSLerContext, m Cruder, filter Filter, group []string, aggregates ...string) (result []R, err error) {
+	var probe R
+	if len(structFields(reflect.ValueOf(&probe).Elem())) != len(group)+len(aggregates) {
+		err = errors.New("GroupBy result fields count does not match group and aggregate columns")
+		return
+	}
+	if err = checkSearch(m, filter); err != nil {
+		return
+	}
+	for _, column := range group {
+		if err = checkIdentifier(column); err != nil {
+			return
+		}
+	}
+	group = quoteIdents(group)
+	list := append(append([]string{}, group...), aggregates...)
+	query, args := groupQuery(m, list, group, filter)
+	rows, err := queryContext(withOperation(ctx, OpSearch, m), ds, query, args...)
+	if err != nil {
+		err = dbError(err)
+		return
+	}
+	defer rows.Close()
+	result = make([]R, 0)
+	for rows.Next() {
+		var row R
+		if err = rows.Scan(structFields(reflect.ValueOf(&row).Elem())...); err != nil {
+			return
+		}
+		result = append(result, row)
+	}
+	err = dbError(rows.Err())
+	return
+}
+
+// Links to exported fields of struct
+func structFields(v reflect.Value) (links []interface{}) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath == "" {
+			links = append(links, v.Field(i).Addr().Interface())
+		}
+	}
+	return
+}
+
+// SQL aggregate Query. Order and limits of Query are dropped, other filters become subquery
+func aggregateQuery(m Cruder, expression string, filter Filter) (string, []interface{}) {
+	if unscope(filter) == nil {
+		return selectQuery(m, expression, filter), nil
+	}
+	if q, ok := unscope(filter).(*Query); ok {
+		cp := q.clone().Limit(0).Offset(0)
+		cp.orders = nil
+		return selectQuery(m, expression, rescope(filter, cp)), Arguments(cp)
+	}
+	return "SELECT " + expression + " FROM (" + SearchQuery(m, filter) + ") AS " + quoteIdent(tableAlias(m)), Arguments(filter)
+}
+
+// SQL group Query, GROUP BY is placed before order and limits of Query
+func groupQuery(m Cruder, list []string, group []string, filter Filter) (string, []interface{}) {
+	groupBy := ""
+	if len(group) > 0 {
+		groupBy = " GROUP BY " + strings.Join(group, ", ")
+	}
+	if q, ok := unscope(filter).(*Query); ok {
+		cp := q.clone().Limit(0).Offset(0)
+		cp.orders = nil
+		query := selectQuery(m, strings.Join(list, ", "), rescope(filter, cp)) + groupBy
+		if len(q.orders) > 0 {
+			query += " ORDER BY " + strings.Join(q.orders, ", ")
+		}
+		if q.limit > 0 {
+			query += " LIMIT " + strconv.Itoa(q.limit)
+		}
+		if q.offset > 0 {
+			query += " OFFSET " + strconv.Itoa(q.offset)
+		}
+		return query, Arguments(cp)
+	}
+	if unscope(filter) == nil {
+		return selectQuery(m, strings.Join(list, ", "), filter) + groupBy, nil
+	}
+	return "SELECT " + strings.Join(list, ", ") + " FROM (" + SearchQuery(m, filter) + ") AS " + quoteIdent(tableAlias(m)) + groupBy, Arguments(filter)
+}
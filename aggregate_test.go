@@ -0,0 +1,107 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestAggregateQuotesColumn(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{columns: []string{"sum"}, rows: [][]driver.Value{{int64(3)}}})
+	sum, err := Sum[int64](db, &probe{}, "order", NewQuery().Where("name = ?", "a"))
+	if err != nil || sum != 3 {
+		t.Fatalf("Sum = %d, %v", sum, err)
+	}
+	want := `SELECT SUM("order") FROM "public"."probe" WHERE (name = $1)`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %q", queries, want)
+	}
+	if _, err = Max[int64](db, &probe{}, `order") FROM x; --`, nil); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("error %v, want ErrInvalidIdentifier", err)
+	}
+}
+
+func TestGroupByQuotesColumns(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{columns: []string{"order", "count"}, rows: [][]driver.Value{{int64(1), int64(2)}}})
+	rows, err := GroupBy[struct {
+		Order int64
+		Count int64
+	}](db, &probe{}, NewQuery().OrderBy(`"order"`), []string{"order"}, "COUNT(*)")
+	if err != nil || len(rows) != 1 || rows[0].Count != 2 {
+		t.Fatalf("GroupBy = %v, %v", rows, err)
+	}
+	want := `SELECT "order", COUNT(*) FROM "public"."probe" GROUP BY "order" ORDER BY "order"`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %q", queries, want)
+	}
+	_, err = GroupBy[struct{ Name string }](db, &probe{}, nil, []string{"name'"})
+	if !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("error %v, want ErrInvalidIdentifier", err)
+	}
+}
+
+func TestCount(t *testing.T) {
+	cases := []struct {
+		model  Cruder
+		filter Filter
+		want   string
+		args   int
+	}{
+		{&probe{}, nil, `SELECT COUNT(*) FROM "public"."probe"`, 0},
+		{&probe{}, NewQuery().Where("name = ?", "a").OrderBy("name").Limit(5).Offset(10), `SELECT COUNT(*) FROM "public"."probe" WHERE (name = $1)`, 1},
+		{&document{}, nil, `SELECT COUNT(*) FROM (SELECT * FROM "document" WHERE "deleted_at" IS NULL) AS "document"`, 0},
+		{&document{}, NewQuery().Where("title = ?", "a"), `SELECT COUNT(*) FROM "document" WHERE "deleted_at" IS NULL AND ((title = $1))`, 1},
+		{&document{}, WithDeleted(NewQuery().Where("title = ?", "a")), `SELECT COUNT(*) FROM "document" WHERE (title = $1)`, 1},
+		{&document{}, WithDeleted(nil), `SELECT COUNT(*) FROM "document"`, 0},
+	}
+	for _, c := range cases {
+		db := newTestDB(t)
+		db.push(testResult{columns: []string{"count"}, rows: [][]driver.Value{{int64(4)}}})
+		count, err := Count(db, c.model, c.filter)
+		if err != nil || count != 4 {
+			t.Fatalf("Count = %d, %v", count, err)
+		}
+		if queries := db.executed(); len(queries) != 1 || queries[0] != c.want || len(db.args[0]) != c.args {
+			t.Errorf("executed %q %v, want %s", queries, db.args, c.want)
+		}
+	}
+}
+
+func TestExists(t *testing.T) {
+	cases := []struct {
+		filter Filter
+		want   string
+	}{
+		{NewQuery().Where("title = ?", "a"), `SELECT EXISTS (SELECT "id", "title", "version", "created_at", "updated_at", "deleted_at" FROM "document" WHERE "deleted_at" IS NULL AND ((title = $1)))`},
+		{WithDeleted(NewQuery().Where("title = ?", "a")), `SELECT EXISTS (SELECT "id", "title", "version", "created_at", "updated_at", "deleted_at" FROM "document" WHERE (title = $1))`},
+	}
+	for _, c := range cases {
+		for _, exists := range []bool{true, false} {
+			db := newTestDB(t)
+			db.push(testResult{columns: []string{"exists"}, rows: [][]driver.Value{{exists}}})
+			got, err := Exists(db, &document{}, c.filter)
+			if err != nil || got != exists {
+				t.Fatalf("Exists = %v, %v, want %v", got, err, exists)
+			}
+			if queries := db.executed(); len(queries) != 1 || queries[0] != c.want || len(db.args[0]) != 1 {
+				t.Errorf("executed %q %v, want %s", queries, db.args, c.want)
+			}
+		}
+	}
+}
+
+func TestCountRejectsInvalidColumn(t *testing.T) {
+	db := newTestDB(t)
+	filter := NewQuery().In(`name"`, "a")
+	if _, err := Count(db, &probe{}, filter); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("Count error %v, want ErrInvalidIdentifier", err)
+	}
+	if _, err := Exists(db, &probe{}, filter); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("Exists error %v, want ErrInvalidIdentifier", err)
+	}
+	if queries := db.executed(); len(queries) != 0 {
+		t.Errorf("executed %q", queries)
+	}
+}
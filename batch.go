@@ -0,0 +1,201 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Postgres limit of bind parameters per statement
+const maxQueryParams = 65535
+
+// Save slice of models with multi-row statements
+func SaveAll(ds DSLer, models []Cruder) (err error) {
+	return SaveAllContext(context.Background(), WithContext(ds), models)
+}
+
+// Save slice of models with multi-row statements and context.
+// Models without sequences are upserted on primary key, models with empty
+// sequences are inserted, both in Postgres chunks below the bind parameter limit
+// and one by one in other dialects.
+// Models with equal primary keys are upserted by different statements in input order.
+// Versioned models are upserted one by one to check their versions as Save does.
+// Models with filled sequences are updated one by one.
+// RETURNING rows are scanned back into the models in input order.
+func SaveAllContext(ctx context.Context, ds DSLerContext, models []Cruder) (err error) {
+	if len(models) == 0 {
+		return
+	}
+	table := models[0].TableName()
+	var creates, upserts, updates []Cruder
+	for _, m := range models {
+		if m.TableName() != table {
+			err = errors.New("all models must belong to table " + table)
+			return
+		}
+		_, attrLink := m.Sequences()
+		if isUpdate(m) {
+			updates = append(updates, m)
+			continue
+		}
+		if err = beforeSave(ctx, ds, m, true); err != nil {
+			return
+		}
+		if len(attrLink) == 0 {
+			upserts = append(upserts, m)
+		} else {
+			creates = append(creates, m)
+		}
+	}
+	if err = saveChunks(ctx, ds, creates, false); err != nil {
+		return
+	}
+	if err = saveChunks(ctx, ds, upserts, true); err != nil {
+		return
+	}
+	for _, m := range updates {
+		if _, err = update(ctx, ds, fieldsOf(m)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func saveChunks(ctx context.Context, ds DSLerContext, models []Cruder, onConflict bool) (err error) {
+	if len(models) == 0 {
+		return
+	}
+	d := dialectOf(ds)
+	_, _, versioned := versionColumn(models[0])
+	if !orderedReturning(d) || onConflict && versioned {
+		return saveEach(ctx, ds, models, onConflict)
+	}
+	names, _ := insertionColumns(models[0])
+	size := len(models)
+	if len(names) > 0 {
+		size = maxQueryParams / len(names)
+	}
+	for _, chunk := range splitChunks(models, size, onConflict) {
+		if err = saveBatch(ctx, ds, chunk, onConflict); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Split models into chunks of size. Upserted models with primary key already present in chunk
+// start next chunk: Postgres can not update one row twice in a statement
+func splitChunks(models []Cruder, size int, onConflict bool) (chunks [][]Cruder) {
+	start := 0
+	keys := make(map[string]bool)
+	for i, m := range models {
+		var key string
+		if onConflict {
+			key = primaryKeyString(m)
+		}
+		if i-start == size || onConflict && keys[key] {
+			chunks = append(chunks, models[start:i])
+			start = i
+			keys = make(map[string]bool)
+		}
+		keys[key] = true
+	}
+	return append(chunks, models[start:])
+}
+
+// Primary key values of model comparable as map key
+func primaryKeyString(m Cruder) string {
+	_, links := m.PrimaryKey()
+	values := make([]interface{}, len(links))
+	for key, link := range links {
+		values[key] = reflect.Indirect(reflect.ValueOf(link)).Interface()
+	}
+	return fmt.Sprintf("%#v", values)
+}
+
+// Multi-row RETURNING lists rows in VALUES order. SQLite documents the order as arbitrary
+func orderedReturning(d Dialect) bool {
+	return d == Postgres
+}
+
+// Save models one by one for dialect without ordered RETURNING or versioned upserts
+func saveEach(ctx context.Context, ds DSLerContext, models []Cruder, onConflict bool) (err error) {
+	for _, m := range models {
+		f := fieldsOf(m)
+		if onConflict {
+			_, err = upsertRow(ctx, ds, f)
+		} else {
+			_, err = insertRow(ctx, ds, f)
+		}
+		if err != nil {
+			return
+		}
+		if err = saved(ctx, ds, f); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func saveBatch(ctx context.Context, ds DSLerContext, models []Cruder, onConflict bool) (err error) {
+	query, insertions := getBatchSaveQuery(dialectOf(ds), models, onConflict)
+	op := OpCreate
+	if onConflict {
+		op = OpUpsert
+	}
+	rows, err := queryContext(withOperation(ctx, op, models[0]), ds, query, insertions...)
+	if err != nil {
+		err = dbError(err)
+		return
+	}
+	defer rows.Close()
+	for _, m := range models {
+		if !rows.Next() {
+			if err = dbError(rows.Err()); err == nil {
+				err = errors.New("batch save returned less rows than models")
+			}
+			return
+		}
+		if err = parse(rows, m); err != nil {
+			return
+		}
+	}
+	if err = dbError(rows.Err()); err != nil {
+		return
+	}
+	rows.Close()
+	for _, m := range models {
+		if err = saved(ctx, ds, fieldsOf(m)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// SQL multi-row insert Query, upsert of versioned models has no version check and is saved one by one
+func getBatchSaveQuery(d Dialect, models []Cruder, onConflict bool) (query string, insertions []interface{}) {
+	names, _ := insertionColumns(models[0])
+	values := make([]string, 0, len(models))
+	count := 0
+	for _, m := range models {
+		_, links := insertionColumns(m)
+		insertions = append(insertions, links...)
+		params := make([]string, len(links))
+		for i := range links {
+			count++
+			params[i] = "$" + strconv.Itoa(count)
+		}
+		values = append(values, "("+strings.Join(params, ", ")+")")
+	}
+
+	query = `INSERT INTO ` + quoteIdent(models[0].TableName()) + ` (` + strings.Join(quoteIdents(names), ",") + `) VALUES ` + strings.Join(values, ", ")
+	if onConflict {
+		query += `
+	` + d.Upsert(upsertClause(models[0], nil))
+	}
+	query += returning(d, models[0]) + `;`
+	return
+}
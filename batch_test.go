@@ -0,0 +1,136 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+// Versioned model with natural key
+type revision struct {
+	Code    string
+	Version int64
+}
+
+func (m *revision) Columns() ([]string, []interface{}) {
+	return []string{"version"}, []interface{}{&m.Version}
+}
+
+func (m *revision) PrimaryKey() ([]string, []interface{}) {
+	return []string{"code"}, []interface{}{&m.Code}
+}
+
+func (m *revision) Sequences() ([]string, []interface{}) {
+	return nil, nil
+}
+
+func (m *revision) TableName() string {
+	return "revision"
+}
+
+func (m *revision) Validate() error {
+	return nil
+}
+
+func (m *revision) VersionColumn() (string, interface{}) {
+	return "version", &m.Version
+}
+
+func TestSaveAllUpsertsDuplicateKeysSeparately(t *testing.T) {
+	db := newTestDB(t)
+	columns := []string{"code", "name"}
+	db.push(
+		testResult{columns: columns, rows: [][]driver.Value{{"a", "x"}, {"b", "y"}}},
+		testResult{columns: columns, rows: [][]driver.Value{{"a", "z"}}},
+	)
+	models := []Cruder{&tag{Code: "a", Name: "x"}, &tag{Code: "b", Name: "y"}, &tag{Code: "a", Name: "z"}}
+	if err := SaveAll(db, models); err != nil {
+		t.Fatal(err)
+	}
+	queries := db.executed()
+	if len(queries) != 2 {
+		t.Fatalf("executed %q", queries)
+	}
+	want := `INSERT INTO "tag" ("code","name") VALUES ($1, $2), ($3, $4) ON CONFLICT ("code") DO UPDATE SET "code" = EXCLUDED."code", "name" = EXCLUDED."name" RETURNING "code", "name";`
+	if queries[0] != want {
+		t.Errorf("executed %s, want %s", queries[0], want)
+	}
+	if !strings.Contains(queries[1], "VALUES ($1, $2) ON CONFLICT") {
+		t.Errorf("executed %s", queries[1])
+	}
+}
+
+func TestSaveAllChecksVersionOfUpsert(t *testing.T) {
+	db := newTestDB(t)
+	columns := []string{"code", "version"}
+	db.push(testResult{columns: columns, rows: [][]driver.Value{{"a", int64(2)}}}, testResult{columns: columns})
+	models := []Cruder{&revision{Code: "a", Version: 1}, &revision{Code: "b", Version: 1}}
+	if err := SaveAll(db, models); err != ErrStaleObject {
+		t.Fatalf("SaveAll = %v, want ErrStaleObject", err)
+	}
+	queries := db.executed()
+	if len(queries) != 2 {
+		t.Fatalf("executed %q", queries)
+	}
+	for key, query := range queries {
+		if !strings.Contains(query, `WHERE "revision"."version" = $3`) {
+			t.Errorf("executed %s", query)
+		}
+		if args := db.args[key]; len(args) != 3 {
+			t.Errorf("arguments %v", args)
+		}
+	}
+	if v := models[0].(*revision).Version; v != 2 {
+		t.Errorf("version %d, want 2", v)
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	models := []Cruder{&tag{Code: "a"}, &tag{Code: "b"}, &tag{Code: "c"}, &tag{Code: "a"}, &tag{Code: "b"}}
+	cases := []struct {
+		size       int
+		onConflict bool
+		want       []int
+	}{
+		{10, false, []int{5}},
+		{2, false, []int{2, 2, 1}},
+		{10, true, []int{3, 2}},
+		{2, true, []int{2, 2, 1}},
+	}
+	for _, c := range cases {
+		chunks := splitChunks(models, c.size, c.onConflict)
+		sizes := make([]int, len(chunks))
+		for key, chunk := range chunks {
+			sizes[key] = len(chunk)
+		}
+		if len(sizes) != len(c.want) {
+			t.Errorf("size %d, upsert %v: chunks %v, want %v", c.size, c.onConflict, sizes, c.want)
+			continue
+		}
+		for key := range sizes {
+			if sizes[key] != c.want[key] {
+				t.Errorf("size %d, upsert %v: chunks %v, want %v", c.size, c.onConflict, sizes, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSaveAllInsertsOneByOneInSQLite(t *testing.T) {
+	db := newTestDB(t)
+	db.push(probeRow(1), probeRow(2))
+	models := []Cruder{&probe{Name: "a"}, &probe{Name: "b"}}
+	if err := SaveAll(WithDialect(db, SQLite), models); err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "public"."probe" ("name","order") VALUES ( ?1, ?2) RETURNING "id", "name", "order";`
+	queries := db.executed()
+	if len(queries) != 2 || queries[0] != want || queries[1] != want {
+		t.Errorf("executed %q, want %s twice", queries, want)
+	}
+	for key, m := range models {
+		if id := m.(*probe).Id; id != int64(key+1) {
+			t.Errorf("model %d got id %d", key, id)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package crud
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Filter wrapper which lets DeleteWhere and UpdateWhere affect every row when filter is empty
+type fullTableFilter struct {
+	Filter
+}
+
+// Allow bulk delete or update by empty filter. Without it such call fails with ErrFullTable
+func AllowFullTable(filter Filter) Filter {
+	return fullTableFilter{filter}
+}
+
+// Filter without AllowFullTable wrapper, WithDeleted wrapper is kept
+func fullTable(filter Filter) (Filter, bool) {
+	if f, ok := filter.(fullTableFilter); ok {
+		return f.Filter, true
+	}
+	if u, ok := filter.(unscopedFilter); ok {
+		if f, ok := u.Filter.(fullTableFilter); ok {
+			return WithDeleted(f.Filter), true
+		}
+	}
+	return filter, false
+}
+
+// WHERE clause of bulk statement matching the same rows as SearchQuery, arguments are numbered from 1.
+// Query without limits is applied directly, other filters select primary keys
+func bulkWhere(m Cruder, filter Filter) (where string, args []interface{}, err error) {
+	filter, all := fullTable(filter)
+	if unscope(filter) == nil {
+		filter = rescope(filter, NewQuery())
+	}
+	if !all && emptyFilter(unscope(filter)) {
+		err = ErrFullTable
+		return
+	}
+	if err = checkSearch(m, filter); err != nil {
+		return
+	}
+	extra := softDeleteCondition(m)
+	if _, ok := filter.(unscopedFilter); ok {
+		extra = ""
+	}
+	if q, ok := unscope(filter).(*Query); ok && q.limit == 0 && q.offset == 0 {
+		cp := q.clone()
+		cp.orders = nil
+		where, args = cp.render(extra)
+		return
+	}
+	primary, _ := m.PrimaryKey()
+	if len(primary) == 0 {
+		err = ErrNoPrimaryKey
+		return
+	}
+	keys := strings.Join(quoteIdents(primary), ", ")
+	where = "WHERE (" + keys + ") IN (SELECT " + keys + " FROM (" + SearchQuery(m, filter) + ") AS " + quoteIdent("crud_filter") + ")"
+	args = Arguments(filter)
+	return
+}
+
+// Filter without conditions, order and limits alone do not restrict affected rows
+func emptyFilter(filter Filter) bool {
+	if q, ok := filter.(*Query); ok {
+		return len(q.conditions)+len(q.scopes) == 0
+	}
+	return !strings.Contains(strings.ToUpper(filter.String()), "WHERE")
+}
+
+// SQL bulk delete Query, soft deleters get deletion timestamp instead of removal
+func getDeleteWhereQuery(d Dialect, m Cruder, filter Filter, ret bool) (query string, args []interface{}, err error) {
+	sd, soft := m.(SoftDeleter)
+	if soft {
+		inner, all := fullTable(filter)
+		filter = unscope(inner)
+		if all {
+			filter = AllowFullTable(filter)
+		}
+	}
+	where, args, err := bulkWhere(m, filter)
+	if err != nil {
+		return
+	}
+	table := quoteIdent(m.TableName())
+	if soft {
+		name, _ := sd.SoftDeleteColumn()
+		args = append(args, Clock())
+		query = "UPDATE " + table + " SET " + quoteIdent(name) + " = $" + strconv.Itoa(len(args))
+	} else {
+		query = "DELETE FROM " + table
+	}
+	query = strings.TrimSpace(query + " " + where)
+	if ret {
+		query += returning(d, m)
+	}
+	return
+}
+
+// SQL bulk update Query assigning set values. Version is incremented and updated_at is stamped
+func getUpdateWhereQuery(d Dialect, m Cruder, set map[string]interface{}, filter Filter, ret bool) (query string, args []interface{}, err error) {
+	where, args, err := bulkWhere(m, filter)
+	if err != nil {
+		return
+	}
+	values := make(map[string]interface{}, len(set)+1)
+	for name, value := range set {
+		values[name] = value
+	}
+	if name, _, ok := updatedAtColumn(m); ok {
+		if _, is := values[name]; !is {
+			values[name] = Clock()
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if err = checkIdentifier(name); err != nil {
+			return
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assignments := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		args = append(args, values[name])
+		assignments = append(assignments, quoteIdent(name)+" = $"+strconv.Itoa(len(args)))
+	}
+	if inc := versionIncrement(m, ""); inc != "" {
+		assignments = append(assignments, inc)
+	}
+	query = "UPDATE " + quoteIdent(m.TableName()) + " SET " + strings.Join(assignments, ", ")
+	query = strings.TrimSpace(query + " " + where)
+	if ret {
+		query += returning(d, m)
+	}
+	return
+}
+
+// Delete rows matched by filter without hooks, soft deleters get deletion timestamp.
+// Empty filter fails with ErrFullTable unless it is wrapped by AllowFullTable
+func DeleteWhere(ds DSLer, m Cruder, filter Filter) (int64, error) {
+	return DeleteWhereContext(context.Background(), WithContext(ds), m, filter)
+}
+
+// Delete rows matched by filter with context
+func DeleteWhereContext(ctx context.Context, ds DSLerContext, m Cruder, filter Filter) (affected int64, err error) {
+	err = instrument(ctx, OpDelete, m, func(ctx context.Context) (err error) {
+		if err = checkIdentifiers(m); err != nil {
+			return
+		}
+		query, args, err := getDeleteWhereQuery(dialectOf(ds), m, filter, false)
+		if err != nil {
+			return
+		}
+		affected, err = execAffected(withOperation(ctx, OpDelete, m), ds, query, args)
+		return
+	})
+	return
+}
+
+// Delete rows matched by filter and return them
+func DeleteWhereReturning[T any, PT Model[T]](ds DSLer, filter Filter) ([]T, error) {
+	return DeleteWhereReturningContext[T, PT](context.Background(), WithContext(ds), filter)
+}
+
+// Delete rows matched by filter and return them with context
+func DeleteWhereReturningContext[T any, PT Model[T]](ctx context.Context, ds DSLerContext, filter Filter) (result []T, err error) {
+	m := PT(new(T))
+	err = instrument(ctx, OpDelete, m, func(ctx context.Context) (err error) {
+		d := dialectOf(ds)
+		if !d.Returning() {
+			return ErrReturningNotSupported
+		}
+		query, args, err := getDeleteWhereQuery(d, m, filter, true)
+		if err != nil {
+			return
+		}
+		result, err = scanModels[T, PT](ctx, ds, OpDelete, query, args)
+		return
+	})
+	return
+}
+
+// Update rows matched by filter assigning column values without hooks.
+// Empty filter fails with ErrFullTable unless it is wrapped by AllowFullTable
+func UpdateWhere(ds DSLer, m Cruder, set map[string]interface{}, filter Filter) (int64, error) {
+	return UpdateWhereContext(context.Background(), WithContext(ds), m, set, filter)
+}
+
+// Update rows matched by filter with context
+func UpdateWhereContext(ctx context.Context, ds DSLerContext, m Cruder, set map[string]interface{}, filter Filter) (affected int64, err error) {
+	if len(set) == 0 {
+		return
+	}
+	err = instrument(ctx, OpUpdate, m, func(ctx context.Context) (err error) {
+		if err = checkIdentifiers(m); err != nil {
+			return
+		}
+		query, args, err := getUpdateWhereQuery(dialectOf(ds), m, set, filter, false)
+		if err != nil {
+			return
+		}
+		affected, err = execAffected(withOperation(ctx, OpUpdate, m), ds, query, args)
+		return
+	})
+	return
+}
+
+// Update rows matched by filter and return them
+func UpdateWhereReturning[T any, PT Model[T]](ds DSLer, set map[string]interface{}, filter Filter) ([]T, error) {
+	return UpdateWhereReturningContext[T, PT](context.Background(), WithContext(ds), set, filter)
+}
+
+// Update rows matched by filter and return them with context
+func UpdateWhereReturningContext[T any, PT Model[T]](ctx context.Context, ds DSLerContext, set map[string]interface{}, filter Filter) (result []T, err error) {
+	if len(set) == 0 {
+		return make([]T, 0), nil
+	}
+	m := PT(new(T))
+	err = instrument(ctx, OpUpdate, m, func(ctx context.Context) (err error) {
+		d := dialectOf(ds)
+		if !d.Returning() {
+			return ErrReturningNotSupported
+		}
+		query, args, err := getUpdateWhereQuery(d, m, set, filter, true)
+		if err != nil {
+			return
+		}
+		result, err = scanModels[T, PT](ctx, ds, OpUpdate, query, args)
+		return
+	})
+	return
+}
+
+// Execute statement and count affected rows
+func execAffected(ctx context.Context, ds DSLerContext, query string, args []interface{}) (affected int64, err error) {
+	result, err := execContext(ctx, ds, query, args...)
+	if err != nil {
+		return 0, dbError(err)
+	}
+	return result.RowsAffected()
+}
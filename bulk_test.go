@@ -0,0 +1,108 @@
+package crud
+
+import (
+	"errors"
+	"testing"
+)
+
+// Filter of other package without WHERE clause
+type rawFilter string
+
+func (f rawFilter) String() string {
+	return string(f)
+}
+
+func (f rawFilter) GetArguments() []interface{} {
+	return nil
+}
+
+func TestBulkWhereRefusesEmptyFilter(t *testing.T) {
+	filters := map[string]Filter{
+		"nil":         nil,
+		"empty":       NewQuery(),
+		"order":       NewQuery().OrderBy("id"),
+		"limit":       NewQuery().Limit(10),
+		"raw order":   rawFilter("ORDER BY id"),
+		"raw empty":   rawFilter(""),
+		"with delete": WithDeleted(NewQuery().OrderBy("id")),
+	}
+	for name, filter := range filters {
+		if _, _, err := getDeleteWhereQuery(Postgres, &probe{}, filter, false); !errors.Is(err, ErrFullTable) {
+			t.Errorf("%s: delete error %v, want ErrFullTable", name, err)
+		}
+		set := map[string]interface{}{"order": 1}
+		if _, _, err := getUpdateWhereQuery(Postgres, &probe{}, set, filter, false); !errors.Is(err, ErrFullTable) {
+			t.Errorf("%s: update error %v, want ErrFullTable", name, err)
+		}
+	}
+}
+
+func TestDeleteWhereAndUpdateWhereRefuseEmptyFilter(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := DeleteWhere(db, &probe{}, NewQuery().OrderBy("id")); !errors.Is(err, ErrFullTable) {
+		t.Errorf("DeleteWhere error %v, want ErrFullTable", err)
+	}
+	if _, err := UpdateWhere(db, &probe{}, map[string]interface{}{"order": 1}, NewQuery().OrderBy("id")); !errors.Is(err, ErrFullTable) {
+		t.Errorf("UpdateWhere error %v, want ErrFullTable", err)
+	}
+	if queries := db.executed(); len(queries) != 0 {
+		t.Errorf("executed %q", queries)
+	}
+}
+
+func TestBulkQueries(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter Filter
+		query  string
+		args   int
+	}{
+		{"full table", AllowFullTable(nil), `DELETE FROM "public"."probe"`, 0},
+		{"query", NewQuery().Where("name = ?", "a").OrderBy("id"), `DELETE FROM "public"."probe" WHERE (name = $1)`, 1},
+		{"limited query", NewQuery().Where("name = ?", "a").Limit(5),
+			`DELETE FROM "public"."probe" WHERE ("id") IN (SELECT "id" FROM (SELECT "id", "name", "order" FROM "public"."probe" WHERE (name = $1) LIMIT 5) AS "crud_filter")`, 1},
+		{"raw filter", rawFilter("WHERE name <> ''"),
+			`DELETE FROM "public"."probe" WHERE ("id") IN (SELECT "id" FROM (SELECT "id", "name", "order" FROM "public"."probe" WHERE name <> '') AS "crud_filter")`, 0},
+	}
+	for _, c := range cases {
+		query, args, err := getDeleteWhereQuery(Postgres, &probe{}, c.filter, false)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if oneLine(query) != c.query || len(args) != c.args {
+			t.Errorf("%s: got %s %v, want %s", c.name, oneLine(query), args, c.query)
+		}
+	}
+}
+
+func TestSoftDeleteWhere(t *testing.T) {
+	query, args, err := getDeleteWhereQuery(Postgres, &document{}, WithDeleted(NewQuery().Where("title = ?", "a")), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `UPDATE "document" SET "deleted_at" = $2 WHERE "deleted_at" IS NULL AND ((title = $1)) RETURNING "id", "title", "version", "created_at", "updated_at", "deleted_at"`
+	if oneLine(query) != want || len(args) != 2 {
+		t.Errorf("got %s %v", oneLine(query), args)
+	}
+}
+
+func TestUpdateWhere(t *testing.T) {
+	query, args, err := getUpdateWhereQuery(Postgres, &document{}, map[string]interface{}{"title": "b"}, NewQuery().Where("id = ?", 1), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `UPDATE "document" SET "title" = $2, "updated_at" = $3, "version" = "version" + 1 WHERE "deleted_at" IS NULL AND ((id = $1))`
+	if oneLine(query) != want || len(args) != 3 || args[1] != "b" {
+		t.Errorf("got %s %v", oneLine(query), args)
+	}
+	if _, _, err = getUpdateWhereQuery(Postgres, &document{}, map[string]interface{}{`x"y`: 1}, NewQuery().Where("id = 1"), false); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("error %v, want ErrInvalidIdentifier", err)
+	}
+	db := newTestDB(t)
+	db.push(testResult{affected: 2})
+	affected, err := UpdateWhere(db, &probe{}, map[string]interface{}{"order": 3}, NewQuery().In("id", 1, 2))
+	if err != nil || affected != 2 {
+		t.Errorf("UpdateWhere = %d, %v", affected, err)
+	}
+}
@@ -0,0 +1,46 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SQL tx and dbo with context support
+type DSLerContext interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Adapter for DSLer without context methods
+type dslerAdapter struct {
+	DSLer
+}
+
+func (a dslerAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.Query(query, args...)
+}
+
+// *sql.Row can not carry error of done context, so the query runs regardless of it
+func (a dslerAdapter) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return a.QueryRow(query, args...)
+}
+
+func (a dslerAdapter) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.Exec(query, args...)
+}
+
+// Context-aware view of DSLer. *sql.DB and *sql.Tx are returned as is,
+// other implementations are wrapped and only check the context before Query and Exec
+func WithContext(ds DSLer) DSLerContext {
+	if dc, ok := ds.(DSLerContext); ok {
+		return dc
+	}
+	return dslerAdapter{ds}
+}
@@ -0,0 +1,76 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+// DSLer without context methods
+type plainDSLer struct {
+	db *sql.DB
+}
+
+func (p plainDSLer) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return p.db.Query(query, args...)
+}
+
+func (p plainDSLer) QueryRow(query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRow(query, args...)
+}
+
+func (p plainDSLer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return p.db.Exec(query, args...)
+}
+
+type contextKey struct{}
+
+func TestWithContext(t *testing.T) {
+	db := newTestDB(t)
+	if ds := WithContext(db); ds != DSLerContext(db) {
+		t.Errorf("DSLerContext is wrapped: %T", ds)
+	}
+	if ds := WithContext(plainDSLer{db.DB}); ds != (dslerAdapter{plainDSLer{db.DB}}) {
+		t.Errorf("DSLer is not adapted: %T", ds)
+	}
+}
+
+func TestContextReachesDriver(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.WithValue(context.Background(), contextKey{}, "request")
+	db.push(probeRow(5), testResult{affected: 1})
+	if _, err := LoadContext(ctx, db, &probe{Id: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if db.ctx == nil || db.ctx.Value(contextKey{}) != "request" {
+		t.Errorf("Load context is not passed to driver")
+	}
+	db.ctx = nil
+	if err := DeleteContext(ctx, db, &probe{Id: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if db.ctx == nil || db.ctx.Value(contextKey{}) != "request" {
+		t.Errorf("Delete context is not passed to driver")
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	db := newTestDB(t)
+	for _, ds := range []DSLerContext{db, WithContext(plainDSLer{db.DB})} {
+		if _, err := LoadContext(ctx, ds, &probe{Id: 5}); !errors.Is(err, context.Canceled) {
+			t.Errorf("%T: Load error %v, want context.Canceled", ds, err)
+		}
+		if err := DeleteContext(ctx, ds, &probe{Id: 5}); !errors.Is(err, context.Canceled) {
+			t.Errorf("%T: Delete error %v, want context.Canceled", ds, err)
+		}
+		if err := SaveContext(ctx, WithDialect(ds.(DSLer), MySQL).(DSLerContext), &probe{Id: 5}); !errors.Is(err, context.Canceled) {
+			t.Errorf("%T: Save error %v, want context.Canceled", ds, err)
+		}
+	}
+	if queries := db.executed(); len(queries) != 0 {
+		t.Errorf("executed %q", queries)
+	}
+}
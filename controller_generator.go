@@ -125,7 +125,9 @@ func controllerHeader(modelPath string) (buf bytes.Buffer, err error) {
 	imports := []string{}
 	baseImports := []string{
 		`"github.com/dimonrus/porterr"`,
+		`"context"`,
 		`"fmt"`,
+		`"github.com/cadyrov/gocrud"`,
 		`"` + modelPath + `"`,
 	}
 	imports = append(imports, baseImports...)
@@ -206,16 +208,15 @@ func controllerCreate(modelName string) (buf bytes.Buffer, err error) {
 func (f *{{.Import}}Form) Create() (result *{{.Import}}Form, e porterr.IError) {
 	db := base.App.GetBaseDb()
 	result = f
-	tx, err := db.Begin()
-	if err != nil {
+	err := crud.WithTx(context.Background(), db, &crud.TxOptions{}, func(tx crud.DSLer) error {
+		if e = f.save(tx); e != nil {
+			return e
+		}
+		return nil
+	})
+	if err != nil && e == nil {
 		e = porterr.New(porterr.PortErrorDatabaseQuery, "Create {{.Import}} error: " + err.Error())
-		return
-	}
-	if e = f.save(tx); e == nil {
-		tx.Commit()
-		return
 	}
-	tx.Rollback()
 	return
 }
 	`
@@ -233,11 +234,8 @@ func controllerFind(modelName string) (buf bytes.Buffer, err error) {
 //find {{.Import}}
 func (f *{{.Import}}SearchForm) Find() (result {{.Import}}ResultSet, e porterr.IError) {
 	db := base.App.GetBaseDb()
-	filter := godb.SqlFilter{}
-	filter.AddFiledFilter("name", "=", f.Name)
-	filter.AddInFilter("id", int64ToIArr([]int64{f.Id}))
-	md := (&models.{{.Import}}{})
-	res, _, err := md.Search(db, filter)
+	filter := crud.NewQuery().Where("name = ?", f.Name).In("id", f.Id)
+	res, err := crud.Find[models.{{.Import}}](db, filter)
 	if err != nil {
 		e = porterr.New(porterr.PortErrorDatabaseQuery, "Find {{.Import}} error: " + err.Error())
 		return
@@ -264,19 +262,18 @@ func controllerUpdate(modelName string) (buf bytes.Buffer, err error) {
 func (f *{{.Import}}Form) Update() (result *{{.Import}}Form, e porterr.IError) {
 	db := base.App.GetBaseDb()
 	result = f
-	tx, err := db.Begin()
-	if err != nil {
+	err := crud.WithTx(context.Background(), db, &crud.TxOptions{}, func(tx crud.DSLer) error {
+		if e = f.primaryExistsError(tx); e != nil {
+			return e
+		}
+		if e = f.save(tx); e != nil {
+			return e
+		}
+		return nil
+	})
+	if err != nil && e == nil {
 		e = porterr.New(porterr.PortErrorDatabaseQuery, "Update {{.Import}} error: " + err.Error())
-		return
-	}
-	if e = f.primaryExistsError(tx); e != nil {
-		return
-	}
-	if e = f.save(tx); e == nil {
-		tx.Commit()
-		return
 	}
-	tx.Rollback()
 	return
 }
 	`
@@ -292,22 +289,17 @@ func (f *{{.Import}}Form) Update() (result *{{.Import}}Form, e porterr.IError) {
 func controllerDelete(modelName string) (buf bytes.Buffer, err error) {
 	t := `
 //remove {{.Import}}
-func (f *{{.Import}}Form) Delete() (iError rest.IError) {
+func (f *{{.Import}}Form) Delete() (e porterr.IError) {
 	db := base.App.GetBaseDb()
-	tx, err := db.Begin()
-	if err != nil {
-		e = porterr.New(porterr.PortErrorDatabaseQuery, "Delete {{.Import}} error: " + err.Error())
-		return
-	}
-	if e = f.primaryExistsError(tx); e != nil {
-		return
-	}
-	if err = f.{{.Import}}.Delete(tx); err != nil {
-		tx.Rollback()
+	err := crud.WithTx(context.Background(), db, &crud.TxOptions{}, func(tx crud.DSLer) error {
+		if e = f.primaryExistsError(tx); e != nil {
+			return e
+		}
+		return f.{{.Import}}.Delete(tx)
+	})
+	if err != nil && e == nil {
 		e = porterr.New(porterr.PortErrorDatabaseQuery, "Delete {{.Import}} error: " + err.Error())
-		return
 	}
-	tx.Commit()
 	return
 }
 	`
@@ -0,0 +1,322 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Optional DSLer extension for the Postgres COPY protocol,
+// implemented by wrappers over drivers which support it, e.g. PQCopier and PgConnCopier
+type Copier interface {
+	CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error)
+	CopyTo(ctx context.Context, query string, w io.Writer) (int64, error)
+}
+
+// Copier over transaction of lib/pq, which runs COPY FROM STDIN as prepared statement.
+// lib/pq can not COPY TO STDOUT, so CopyTo fails with ErrCopyNotSupported, PgConnCopier supports both
+type PQCopier struct {
+	*sql.Tx
+}
+
+// Copy models into table of lib/pq transaction
+func WithPQCopy(tx *sql.Tx) *PQCopier {
+	return &PQCopier{Tx: tx}
+}
+
+func (c *PQCopier) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (count int64, err error) {
+	stmt, err := c.PrepareContext(ctx, "COPY "+quoteIdent(table)+" ("+strings.Join(quoteIdents(columns), ", ")+") FROM STDIN")
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err = stmt.ExecContext(ctx, row...); err != nil {
+			return
+		}
+	}
+	// call without arguments sends buffered rows and finishes COPY
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return
+	}
+	count = int64(len(rows))
+	return
+}
+
+func (c *PQCopier) CopyTo(ctx context.Context, query string, w io.Writer) (int64, error) {
+	return 0, ErrCopyNotSupported
+}
+
+// Result of COPY, e.g. pgconn.CommandTag
+type CopyTag interface {
+	RowsAffected() int64
+}
+
+// Connection running COPY over reader and writer, e.g. *pgconn.PgConn of pgx
+type PgCopyConn[T CopyTag] interface {
+	CopyFrom(ctx context.Context, r io.Reader, sql string) (T, error)
+	CopyTo(ctx context.Context, w io.Writer, sql string) (T, error)
+}
+
+// Copier over pgconn connection, rows are sent in COPY text format.
+// Connection must be the one of DSLer, e.g. of transaction when DSLer is a transaction
+type PgConnCopier[T CopyTag] struct {
+	DSLer
+	DSLerContext
+	conn PgCopyConn[T]
+}
+
+// Copy with pgconn connection of DSLer, e.g.
+// crud.WithPgConnCopy[pgconn.CommandTag](db, conn.PgConn())
+func WithPgConnCopy[T CopyTag](ds DSLer, conn PgCopyConn[T]) *PgConnCopier[T] {
+	return &PgConnCopier[T]{DSLer: ds, DSLerContext: WithContext(ds), conn: conn}
+}
+
+func (c *PgConnCopier[T]) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (count int64, err error) {
+	var b strings.Builder
+	for _, row := range rows {
+		for key, value := range row {
+			if key > 0 {
+				b.WriteByte('\t')
+			}
+			var text string
+			if text, err = copyText(value); err != nil {
+				return
+			}
+			b.WriteString(text)
+		}
+		b.WriteByte('\n')
+	}
+	tag, err := c.conn.CopyFrom(ctx, strings.NewReader(b.String()), "COPY "+quoteIdent(table)+" ("+strings.Join(quoteIdents(columns), ", ")+") FROM STDIN")
+	if err != nil {
+		return
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (c *PgConnCopier[T]) CopyTo(ctx context.Context, query string, w io.Writer) (count int64, err error) {
+	tag, err := c.conn.CopyTo(ctx, w, query)
+	if err != nil {
+		return
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Value in COPY text format: \N is NULL, backslash and control characters are escaped
+func copyText(value interface{}) (string, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		value = v
+	}
+	switch v := value.(type) {
+	case nil:
+		return `\N`, nil
+	case string:
+		return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(v), nil
+	case []byte:
+		return `\\x` + hex.EncodeToString(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return `\N`, nil
+		}
+		return copyText(rv.Elem().Interface())
+	}
+	if rv.Kind() == reflect.String {
+		return copyText(rv.String())
+	}
+	// numbers and booleans have no special characters
+	return quoteLiteral(value)
+}
+
+// Copier of DSLer or of wrapped DSLer
+func copierOf(ds interface{}) (Copier, bool) {
+	for ds != nil {
+		if c, ok := ds.(Copier); ok {
+			return c, true
+		}
+		w, ok := ds.(dslerWrapper)
+		if !ok {
+			break
+		}
+		ds = w.unwrap()
+	}
+	return nil, false
+}
+
+// Bulk load models with COPY FROM STDIN, creation timestamps are stamped as by Save
+func CopyFrom(ds DSLer, table string, models []Cruder) (int64, error) {
+	return copyFrom(context.Background(), ds, table, models)
+}
+
+// Bulk load models with COPY FROM STDIN and context.
+// Empty table means the table of the first model
+func CopyFromContext(ctx context.Context, ds DSLerContext, table string, models []Cruder) (int64, error) {
+	return copyFrom(ctx, ds, table, models)
+}
+
+func copyFrom(ctx context.Context, ds interface{}, table string, models []Cruder) (count int64, err error) {
+	copier, ok := copierOf(ds)
+	if !ok {
+		err = ErrCopyNotSupported
+		return
+	}
+	if len(models) == 0 {
+		return
+	}
+	if table == "" {
+		table = models[0].TableName()
+	}
+	if err = checkIdentifiers(models[0]); err != nil {
+		return
+	}
+	names, _ := insertionColumns(models[0])
+	rows := make([][]interface{}, 0, len(models))
+	for _, m := range models {
+		if err = stampCreate(m); err != nil {
+			return
+		}
+		if err = validate(m); err != nil {
+			return
+		}
+		_, links := insertionColumns(m)
+		rows = append(rows, links)
+	}
+	count, err = copier.CopyFrom(ctx, table, names, rows)
+	err = dbError(err)
+	return
+}
+
+// Export model rows matched by filter with COPY TO STDOUT, rows are the same as Find returns
+func CopyTo(ds DSLer, m Cruder, filter Filter, w io.Writer) (int64, error) {
+	return copyTo(context.Background(), ds, m, filter, w)
+}
+
+// Export model rows matched by filter with COPY TO STDOUT and context.
+// COPY does not accept bind parameters, so filter arguments are inlined as literals
+func CopyToContext(ctx context.Context, ds DSLerContext, m Cruder, filter Filter, w io.Writer) (int64, error) {
+	return copyTo(ctx, ds, m, filter, w)
+}
+
+func copyTo(ctx context.Context, ds interface{}, m Cruder, filter Filter, w io.Writer) (count int64, err error) {
+	copier, ok := copierOf(ds)
+	if !ok {
+		err = ErrCopyNotSupported
+		return
+	}
+	query, err := getCopyToQuery(m, filter)
+	if err != nil {
+		return
+	}
+	count, err = copier.CopyTo(ctx, query, w)
+	return
+}
+
+// SQL copy to Query, soft deleted rows are excluded unless filter is wrapped with WithDeleted
+func getCopyToQuery(m Cruder, filter Filter) (query string, err error) {
+	if err = checkSearch(m, filter); err != nil {
+		return
+	}
+	query, err = inlineArguments(SearchQuery(m, filter), Arguments(filter))
+	if err != nil {
+		return
+	}
+	query = "COPY (" + query + ") TO STDOUT"
+	return
+}
+
+// Replace $n placeholders outside of quotes with literal values
+func inlineArguments(query string, args []interface{}) (string, error) {
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '\'' {
+			quoted = !quoted
+		}
+		if quoted || c != '$' {
+			b.WriteByte(c)
+			continue
+		}
+		j := i + 1
+		for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+			j++
+		}
+		if j == i+1 {
+			b.WriteByte(c)
+			continue
+		}
+		n, _ := strconv.Atoi(query[i+1 : j])
+		if n < 1 || n > len(args) {
+			return "", fmt.Errorf("no argument for placeholder $%d", n)
+		}
+		literal, err := quoteLiteral(args[n-1])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(literal)
+		i = j - 1
+	}
+	return b.String(), nil
+}
+
+func quoteLiteral(value interface{}) (string, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		value = v
+	}
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		if strings.IndexByte(v, 0) >= 0 {
+			return "", errors.New("string literal contains zero byte")
+		}
+		// escape string syntax does not depend on standard_conforming_strings
+		return "E'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(v) + "'", nil
+	case []byte:
+		return `E'\\x` + hex.EncodeToString(v) + `'::bytea`, nil
+	case time.Time:
+		return "'" + v.Format(time.RFC3339Nano) + "'", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return quoteLiteral(rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64), nil
+	case reflect.String:
+		return quoteLiteral(rv.String())
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	}
+	return "", fmt.Errorf("can not inline argument of type %T", value)
+}
@@ -0,0 +1,220 @@
+package crud
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestQuoteLiteral(t *testing.T) {
+	cases := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, "NULL"},
+		{"it's", `E'it''s'`},
+		{`a\' OR 1=1 --`, `E'a\\'' OR 1=1 --'`},
+		{[]byte{1, 255}, `E'\\x01ff'::bytea`},
+		{int64(-5), "-5"},
+		{true, "true"},
+	}
+	for _, c := range cases {
+		got, err := quoteLiteral(c.value)
+		if err != nil || got != c.want {
+			t.Errorf("quoteLiteral(%v) = %s, %v, want %s", c.value, got, err, c.want)
+		}
+	}
+	if _, err := quoteLiteral("a\x00"); err == nil {
+		t.Error("zero byte is accepted")
+	}
+}
+
+func TestInlineArguments(t *testing.T) {
+	got, err := inlineArguments("WHERE name = $1 AND note <> '$2' AND id > $2", []interface{}{"a", 3})
+	if want := `WHERE name = E'a' AND note <> '$2' AND id > 3`; err != nil || got != want {
+		t.Errorf("got %s, %v, want %s", got, err, want)
+	}
+	if _, err = inlineArguments("WHERE id = $2", []interface{}{1}); err == nil {
+		t.Error("missing argument is accepted")
+	}
+}
+
+func TestPQCopierCopyFrom(t *testing.T) {
+	db := newTestDB(t)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := []Cruder{&probe{Name: "a", Order: 1}, &probe{Name: "b", Order: 2}}
+	count, err := CopyFromContext(context.Background(), WithPQCopy(tx), "", models)
+	if err != nil || count != 2 {
+		t.Fatalf("CopyFrom = %d, %v", count, err)
+	}
+	copyIn := `COPY "public"."probe" ("name", "order") FROM STDIN`
+	want := []string{"BEGIN", copyIn, copyIn, copyIn}
+	queries := db.executed()
+	if len(queries) != len(want) {
+		t.Fatalf("executed %q", queries)
+	}
+	for key := range want {
+		if queries[key] != want[key] {
+			t.Errorf("executed %q, want %q", queries[key], want[key])
+		}
+	}
+	if len(db.args[1]) != 2 || db.args[1][0] != "a" || len(db.args[3]) != 0 {
+		t.Errorf("arguments %v", db.args)
+	}
+}
+
+func TestCopyFromStampsTimestamps(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	defer func(clock func() time.Time) { Clock = clock }(Clock)
+	Clock = func() time.Time { return now }
+	db := newTestDB(t)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &document{Title: "a"}
+	if _, err = CopyFrom(WithPQCopy(tx), "", []Cruder{m}); err != nil {
+		t.Fatal(err)
+	}
+	if !m.CreatedAt.Equal(now) || !m.UpdatedAt.Equal(now) {
+		t.Errorf("created_at %v, updated_at %v, want %v", m.CreatedAt, m.UpdatedAt, now)
+	}
+}
+
+func TestCopyNotSupported(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := CopyFrom(db, "", []Cruder{&probe{}}); err != ErrCopyNotSupported {
+		t.Errorf("error %v, want ErrCopyNotSupported", err)
+	}
+}
+
+func TestCopyToQuery(t *testing.T) {
+	cases := []struct {
+		filter Filter
+		want   string
+	}{
+		{nil, `COPY (SELECT "id", "title", "version", "created_at", "updated_at", "deleted_at" FROM (SELECT * FROM "document" WHERE "deleted_at" IS NULL) AS "document") TO STDOUT`},
+		{NewQuery().Where("title = ?", "a"), `COPY (SELECT "id", "title", "version", "created_at", "updated_at", "deleted_at" FROM "document" WHERE "deleted_at" IS NULL AND ((title = E'a'))) TO STDOUT`},
+		{WithDeleted(NewQuery().Where("id > ?", 1)), `COPY (SELECT "id", "title", "version", "created_at", "updated_at", "deleted_at" FROM "document" WHERE (id > 1)) TO STDOUT`},
+	}
+	for _, c := range cases {
+		query, err := getCopyToQuery(&document{}, c.filter)
+		if err != nil || oneLine(query) != c.want {
+			t.Errorf("got %s, %v, want %s", oneLine(query), err, c.want)
+		}
+	}
+}
+
+// Rows count of fake COPY
+type testCopyTag int64
+
+func (t testCopyTag) RowsAffected() int64 {
+	return int64(t)
+}
+
+// pgconn connection receiving and sending COPY data
+type testCopyConn struct {
+	sql  string
+	in   string
+	out  string
+	rows int64
+}
+
+func (c *testCopyConn) CopyFrom(ctx context.Context, r io.Reader, sql string) (testCopyTag, error) {
+	data, err := io.ReadAll(r)
+	c.sql, c.in = sql, string(data)
+	return testCopyTag(c.rows), err
+}
+
+func (c *testCopyConn) CopyTo(ctx context.Context, w io.Writer, sql string) (testCopyTag, error) {
+	c.sql = sql
+	_, err := io.WriteString(w, c.out)
+	return testCopyTag(c.rows), err
+}
+
+// Copier writing fixed output for any query
+type testCopier struct {
+	*testDB
+	query string
+}
+
+func (c *testCopier) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	return 0, ErrCopyNotSupported
+}
+
+func (c *testCopier) CopyTo(ctx context.Context, query string, w io.Writer) (int64, error) {
+	c.query = query
+	_, err := io.WriteString(w, "1\n2\n")
+	return 2, err
+}
+
+func TestCopyToCopier(t *testing.T) {
+	c := &testCopier{testDB: newTestDB(t)}
+	var out bytes.Buffer
+	count, err := CopyTo(WithDialect(c, Postgres), &probe{}, NewQuery().Where("name = ?", "a"), &out)
+	if err != nil || count != 2 || out.String() != "1\n2\n" {
+		t.Fatalf("CopyTo = %d, %v, %q", count, err, out.String())
+	}
+	if want := `COPY (SELECT "id", "name", "order" FROM "public"."probe" WHERE (name = E'a')) TO STDOUT`; oneLine(c.query) != want {
+		t.Errorf("copied %s, want %s", c.query, want)
+	}
+}
+
+func TestPgConnCopierCopyTo(t *testing.T) {
+	conn := &testCopyConn{out: "1\ta\n", rows: 1}
+	ds := WithPgConnCopy[testCopyTag](newTestDB(t), conn)
+	var out bytes.Buffer
+	count, err := CopyTo(ds, &document{}, NewQuery().Where("title = ?", "a"), &out)
+	if err != nil || count != 1 || out.String() != conn.out {
+		t.Fatalf("CopyTo = %d, %v, %q", count, err, out.String())
+	}
+	want := `COPY (SELECT "id", "title", "version", "created_at", "updated_at", "deleted_at" FROM "document" WHERE "deleted_at" IS NULL AND ((title = E'a'))) TO STDOUT`
+	if oneLine(conn.sql) != want {
+		t.Errorf("copied %s, want %s", conn.sql, want)
+	}
+	count, err = CopyToContext(context.Background(), ds, &probe{}, nil, &out)
+	if err != nil || count != 1 || conn.sql != `COPY (SELECT "id", "name", "order" FROM "public"."probe") TO STDOUT` {
+		t.Errorf("CopyToContext = %d, %v, copied %s", count, err, conn.sql)
+	}
+}
+
+func TestPgConnCopierCopyFrom(t *testing.T) {
+	conn := &testCopyConn{rows: 2}
+	models := []Cruder{&probe{Name: "a\tb\\c\nd", Order: 1}, &tag{Code: "x"}}
+	count, err := CopyFrom(WithPgConnCopy[testCopyTag](newTestDB(t), conn), "", models[:1])
+	if err != nil || count != 2 {
+		t.Fatalf("CopyFrom = %d, %v", count, err)
+	}
+	if want := `COPY "public"."probe" ("name", "order") FROM STDIN`; conn.sql != want {
+		t.Errorf("copied %s, want %s", conn.sql, want)
+	}
+	if want := "a\\tb\\\\c\\nd\t1\n"; conn.in != want {
+		t.Errorf("sent %q, want %q", conn.in, want)
+	}
+}
+
+func TestCopyText(t *testing.T) {
+	var missing *string
+	cases := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, `\N`},
+		{missing, `\N`},
+		{"a\tb", `a\tb`},
+		{[]byte{1, 255}, `\\x01ff`},
+		{time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), "2024-05-01T12:00:00Z"},
+		{int64(-5), "-5"},
+		{true, "true"},
+	}
+	for _, c := range cases {
+		if got, err := copyText(c.value); err != nil || got != c.want {
+			t.Errorf("copyText(%v) = %s, %v, want %s", c.value, got, err, c.want)
+		}
+	}
+}
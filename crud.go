@@ -1,6 +1,7 @@
 package crud
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -25,23 +26,55 @@ type DSLer interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
 }
 
+// SQL filter with numbered placeholders, e.g. godb.SqlFilter
+type Filter interface {
+	String() string
+	GetArguments() []interface{}
+}
+
 type Crud struct {
 }
 
-// SQL load Query
+// SQL load Query, soft deleted rows are excluded
 func GetLoadQuery(m Cruder) string {
+	return statementsOf(DefaultDialect, m).load
+}
+
+func buildLoadQuery(m Cruder) string {
 	columns := columnNames(m)
-	table := m.TableName()
+	table := quoteIdent(m.TableName())
 	sql, _ := getSqlPrimary(m, 0)
+	if cond := softDeleteCondition(m); cond != "" {
+		sql += " AND " + cond
+	}
 	return "SELECT " + columns + " FROM " + table + " WHERE " + sql + " ;"
 }
 
+// RETURNING clause of model columns if dialect supports it
+func returning(d Dialect, m Cruder) string {
+	if !d.Returning() {
+		return ""
+	}
+	return `
+	RETURNING ` + columnNames(m)
+}
+
+// SQL load Query including soft deleted rows
+func getSelectQuery(m Cruder) string {
+	return statementsOf(DefaultDialect, m).selectByPk
+}
+
+func buildSelectQuery(m Cruder) string {
+	sql, _ := getSqlPrimary(m, 0)
+	return "SELECT " + columnNames(m) + " FROM " + quoteIdent(m.TableName()) + " WHERE " + sql + " ;"
+}
+
 func getSqlPrimary(m Cruder, cnt int) (sql string, count int) {
 	count = cnt
 	sql = ""
 	nms, _ := m.PrimaryKey()
 	for key, value := range nms {
-		nm := value
+		nm := quoteIdent(value)
 		count++
 		if key == 0 {
 			sql += " " + nm + " = " + fmt.Sprintf("$%v", count) + " "
@@ -53,46 +86,32 @@ func getSqlPrimary(m Cruder, cnt int) (sql string, count int) {
 }
 
 func columnNames(m Cruder) string {
+	return metaOf(m).list
+}
+
+func selectList(m Cruder) string {
 	names := make([]string, 0)
 	primary, _ := m.PrimaryKey()
-	names = append(names, primary...)
+	names = append(names, quoteIdents(primary)...)
 	nms, _ := m.Columns()
-	names = append(names, nms...)
+	names = append(names, quoteIdents(nms)...)
+	if name, _, ok := systemVersion(m); ok {
+		names = append(names, versionExpr(name))
+	}
 	return strings.Join(names, ", ")
 }
 
 func scans(m Cruder) (values []interface{}) {
-	_, attributeLink := m.PrimaryKey()
-	values = append(values, attributeLink...)
-	_, attributeLinks := m.Columns()
-	values = append(values, attributeLinks...)
-	return
+	return fieldsOf(m).scans()
 }
 
-func insertionColumns(m Cruder) (names []string, attributeLinks []interface{}) {
-	prNames, prLinks := m.PrimaryKey()
-	for key, value := range prNames {
-		if !inSequense(m, value) {
-			nm := value
-			attrLink := prLinks[key]
-			names = append(names, nm)
-			attributeLinks = append(attributeLinks, attrLink)
-		}
-	}
-	stNames, stLinks := m.Columns()
-	names = append(names, stNames...)
-	attributeLinks = append(attributeLinks, stLinks...)
-	return
+// Scan destinations in order of SearchQuery columns
+func Scans(m Cruder) []interface{} {
+	return scans(m)
 }
 
-func inSequense(m Cruder, name string) bool {
-	names, _ := m.Sequences()
-	for _, value := range names {
-		if value == name {
-			return true
-		}
-	}
-	return false
+func insertionColumns(m Cruder) (names []string, attributeLinks []interface{}) {
+	return fieldsOf(m).insertion()
 }
 
 func parse(rows *sql.Rows, m Cruder) (err error) {
@@ -103,12 +122,32 @@ func parse(rows *sql.Rows, m Cruder) (err error) {
 
 // Load model
 func Load(dbo DSLer, m Cruder) (find bool, err error) {
+	return LoadContext(context.Background(), WithContext(dbo), m)
+}
+
+// Load model with context
+func LoadContext(ctx context.Context, dbo DSLerContext, m Cruder) (find bool, err error) {
+	return load(ctx, dbo, m, GetLoadQuery(m))
+}
+
+func load(ctx context.Context, dbo DSLerContext, m Cruder, query string) (find bool, err error) {
+	err = instrument(ctx, OpLoad, m, func(ctx context.Context) (err error) {
+		find, err = loadRow(ctx, dbo, m, query)
+		return
+	})
+	return
+}
+
+func loadRow(ctx context.Context, dbo DSLerContext, m Cruder, query string) (find bool, err error) {
+	if err = checkIdentifiers(m); err != nil {
+		return
+	}
 	_, idlinks := m.PrimaryKey()
 	if primaryExists(idlinks) {
 		var iterator *sql.Rows
-		iterator, errQuery := dbo.Query(GetLoadQuery(m), idlinks...)
+		iterator, errQuery := queryContext(withOperation(ctx, OpLoad, m), dbo, query, idlinks...)
 		if errQuery != nil {
-			err = errQuery
+			err = dbError(errQuery)
 			return
 		}
 		defer iterator.Close()
@@ -117,54 +156,111 @@ func Load(dbo DSLer, m Cruder) (find bool, err error) {
 			return
 		}
 
-		err = parse(iterator, m)
-		if err == nil {
-			find = true
+		if err = parse(iterator, m); err != nil {
+			return
 		}
+		iterator.Close()
+		find = true
+		err = Loaded(ctx, dbo, m)
 		return
 	} else {
-		err = errors.New("no primary key specified, nothing for load")
+		err = ErrNoPrimaryKey
 	}
 	return
 }
 
 // SQL delete Query
 func getDeleteQuery(m Cruder) string {
+	return statementsOf(DefaultDialect, m).delete
+}
+
+func buildDeleteQuery(m Cruder) string {
 	sql, _ := getSqlPrimary(m, 0)
-	return "DELETE FROM " + m.TableName() + " WHERE " + sql + " ;"
+	return "DELETE FROM " + quoteIdent(m.TableName()) + " WHERE " + sql + " ;"
 }
 
 // Delete method
 func Delete(dbo DSLer, m Cruder) error {
-	_, idlinks := m.PrimaryKey()
-	_, err := dbo.Exec(getDeleteQuery(m), idlinks...)
-	return err
+	return DeleteContext(context.Background(), WithContext(dbo), m)
+}
+
+// Delete method with context. Soft deleters get deletion timestamp instead of removal
+func DeleteContext(ctx context.Context, dbo DSLerContext, m Cruder) error {
+	return deleteWithHooks(ctx, dbo, m, func(ctx context.Context) error {
+		if sd, ok := m.(SoftDeleter); ok {
+			return softDelete(ctx, dbo, m, sd)
+		}
+		return hardDelete(ctx, dbo, m)
+	})
 }
 
 // SQL update Query
-func getUpdateQuery(m Cruder) (query string, insertions []interface{}) {
-	_, attr := m.PrimaryKey()
-	insertions = append(insertions, attr...)
-	cols, ins := insertionColumns(m)
-	insertions = append(insertions, ins...)
+func getUpdateQuery(d Dialect, m Cruder) (query string, insertions []interface{}) {
+	return getPartialUpdateQuery(d, fieldsOf(m), metaOf(m).insertion)
+}
+
+// SQL update Query of given columns, empty if there is nothing to update
+func getPartialUpdateQuery(d Dialect, f *fields, columns []string) (query string, insertions []interface{}) {
+	m := f.m
+	names, links := f.insertion()
+	insertions = make([]interface{}, 0, len(f.primary)+len(names)+1)
+	insertions = append(insertions, f.primary...)
+	cols := make([]string, 0, len(columns))
+	for key, name := range names {
+		if updatable(m, name) && existsInArrayString(name, columns) {
+			cols = append(cols, name)
+			insertions = append(insertions, links[key])
+		}
+	}
+	if len(cols) == 0 {
+		insertions = nil
+		return
+	}
+	if _, link, ok := versionColumn(m); ok {
+		insertions = append(insertions, link)
+	}
+	query = statementsOf(d, m).update(d, m, cols)
+	return
+}
+
+func buildUpdateQuery(d Dialect, m Cruder, cols []string) string {
 	sqlPrm, iStrt := getSqlPrimary(m, 0)
 	updateCols := ""
 	for i, colname := range cols {
-		updateCols = updateCols + " " + colname + " = $" + strconv.Itoa(i+1+iStrt)
+		updateCols = updateCols + " " + quoteIdent(colname) + " = $" + strconv.Itoa(i+1+iStrt)
 		if i < (len(cols) - 1) {
 			updateCols = updateCols + ", "
 		}
 	}
+	if inc := versionIncrement(m, ""); inc != "" {
+		updateCols += ",  " + inc
+	}
+	if name, _, ok := versionColumn(m); ok {
+		sqlPrm += " AND " + versionExpr(name) + " = $" + strconv.Itoa(iStrt+len(cols)+1) + " "
+	}
+
+	return `UPDATE ` + quoteIdent(m.TableName()) + ` SET ` + updateCols + `
+		WHERE ` + sqlPrm + returning(d, m) + `;`
+}
 
-	query = `UPDATE ` + m.TableName() + ` SET ` + updateCols + `
-		WHERE ` + sqlPrm + ` 
-		RETURNING ` + columnNames(m) + `;`
+func getInsertOnConflictQuery(d Dialect, f *fields) (query string, insertions []interface{}) {
+	m := f.m
+	keyed := f.keyed()
+	_, insertions = f.upsertion(keyed)
+	if _, link, ok := versionColumn(m); ok {
+		insertions = append(insertions, link)
+	}
+	query = statementsOf(d, m).upsert
+	if keyed {
+		query = statementsOf(d, m).upsertKeyed
+	}
 	return
 }
 
-func getInsertOnConflictQuery(m Cruder) (query string, insertions []interface{}) {
-	names, insertions := insertionColumns(m)
-	columns := strings.Join(names, ",")
+// Upsert statement, nil options upsert on primary key assigning updatable columns
+func buildInsertOnConflictQuery(d Dialect, m Cruder, keyed bool, opts *UpsertOptions) string {
+	names, _ := fieldsOf(m).upsertion(keyed)
+	columns := strings.Join(quoteIdents(names), ",")
 	params := ""
 	for i, _ := range names {
 		params = params + " $" + strconv.Itoa(i+1)
@@ -173,27 +269,72 @@ func getInsertOnConflictQuery(m Cruder) (query string, insertions []interface{})
 		}
 	}
 
-	updateCols := ""
-	for i, colname := range names {
-		updateCols = updateCols + " " + colname + " = $" + strconv.Itoa(i+1)
-		if i < (len(names) - 1) {
-			updateCols = updateCols + ", "
-		}
+	clause := upsertClause(m, opts)
+	var where []string
+	count := len(names)
+	if name, _, ok := versionColumn(m); ok {
+		count++
+		where = append(where, quoteIdent(tableAlias(m))+`.`+versionExpr(name)+` = $`+strconv.Itoa(count))
 	}
-	pnames, _ := m.PrimaryKey()
-	onconf := strings.Join(pnames, ",")
-	query = `INSERT INTO ` + m.TableName() + ` (` + columns + `) VALUES (` + params + `)
-	ON CONFLICT (` + onconf + `)
-	DO UPDATE SET
-	` + updateCols + `
-	RETURNING ` + columnNames(m) + `
+	if opts != nil && opts.Where != "" {
+		where = append(where, "("+numberPlaceholders(opts.Where, count)+")")
+	}
+	clause.Where = strings.Join(where, " AND ")
+	ret := returning(d, m)
+	if opts != nil && d == Postgres {
+		// xmax of row version is zero unless it was created by update
+		ret += ", (xmax = 0)"
+	}
+	return `INSERT INTO ` + quoteIdent(m.TableName()) + ` (` + columns + `) VALUES (` + params + `)
+	` + d.Upsert(clause) + ret + `
 	;`
+}
+
+// Upsert clause of options, on primary key assigning updatable columns by default
+func upsertClause(m Cruder, opts *UpsertOptions) (clause UpsertClause) {
+	primary, _ := m.PrimaryKey()
+	clause.Conflict = quoteIdents(primary)
+	if opts != nil {
+		if len(opts.Conflict) > 0 {
+			clause.Conflict = quoteIdents(opts.Conflict)
+		}
+		if opts.Constraint != "" {
+			clause.Constraint = quoteIdent(opts.Constraint)
+		}
+		clause.DoNothing = opts.DoNothing
+	}
+	names, _ := insertionColumns(m)
+	for _, colname := range names {
+		if updatable(m, colname) && opts.updates(colname) {
+			clause.Update = append(clause.Update, quoteIdent(colname))
+		}
+	}
+	if inc := versionIncrement(m, tableAlias(m)); inc != "" {
+		clause.Set = append(clause.Set, inc)
+	}
+	if len(clause.Update) == 0 && len(clause.Set) == 0 {
+		clause.DoNothing = true
+	}
+	if sequences, _ := m.Sequences(); len(sequences) == 1 {
+		clause.Sequence = quoteIdent(sequences[0])
+	}
+	return
+}
+
+func getSaveQuery(d Dialect, f *fields) (query string, insertions []interface{}) {
+	keyed := f.keyed()
+	_, insertions = f.upsertion(keyed)
+	query = statementsOf(d, f.m).insert
+	if keyed {
+		query = statementsOf(d, f.m).insertKeyed
+	}
 	return
 }
 
-func getSaveQuery(m Cruder) (query string, insertions []interface{}) {
-	names, insertions := insertionColumns(m)
-	columns := strings.Join(names, ",")
+// Insert statement, keyed insert includes sequences
+func buildSaveQuery(d Dialect, m Cruder, keyed bool) string {
+	names, _ := fieldsOf(m).upsertion(keyed)
+	columns := strings.Join(quoteIdents(names), ",")
 	params := ""
 	for i, _ := range names {
 		params = params + " $" + strconv.Itoa(i+1)
@@ -202,67 +343,231 @@ func getSaveQuery(m Cruder) (query string, insertions []interface{}) {
 		}
 	}
 
-	query = `INSERT INTO ` + m.TableName() + ` (` + columns + `) VALUES (` + params + `)
-	RETURNING ` + columnNames(m) + `;`
-
-	return
+	return `INSERT INTO ` + quoteIdent(m.TableName()) + ` (` + columns + `) VALUES (` + params + `)` + returning(d, m) + `;`
 }
 
 //Model saver method
 func Save(ds DSLer, m Cruder) (err error) {
-	_, attrLink := m.Sequences()
-	ok:= isUpdate(m)
-	if len(attrLink) == 0 {
-		err = insertOnConflict(ds,m)
-	} else if ok {
-		err = update(ds,m)
+	return SaveContext(context.Background(), WithContext(ds), m)
+}
+
+// Model saver method with context
+func SaveContext(ctx context.Context, ds DSLerContext, m Cruder) (err error) {
+	f := fieldsOf(m)
+	if len(f.sequences) == 0 {
+		_, err = save(ctx, OpUpsert, m, func(ctx context.Context) (bool, error) {
+			return insertOnConflict(ctx, ds, f)
+		})
+	} else if f.keyed() {
+		_, err = save(ctx, OpUpdate, m, func(ctx context.Context) (bool, error) {
+			return update(ctx, ds, f)
+		})
 	} else {
-		err = create(ds,m)
+		_, err = save(ctx, OpCreate, m, func(ctx context.Context) (bool, error) {
+			return create(ctx, ds, f)
+		})
 	}
 	return
 }
 
-func create(ds DSLer, m Cruder) (err error) {
-	if err = m.Validate(); err == nil {
-		query, insertions := getSaveQuery(m)
-		err = ds.QueryRow(query, insertions...).Scan(scans(m)...)
+// Insert model, fails with ErrUniqueViolation if row exists.
+// Sequence columns are inserted when they are set
+func Insert(ds DSLer, m Cruder) (affected bool, err error) {
+	return InsertContext(context.Background(), WithContext(ds), m)
+}
+
+// Insert model with context
+func InsertContext(ctx context.Context, ds DSLerContext, m Cruder) (affected bool, err error) {
+	return save(ctx, OpCreate, m, func(ctx context.Context) (bool, error) {
+		return create(ctx, ds, fieldsOf(m))
+	})
+}
+
+// Update model row by primary key. Not affected if there is no such row or no column has changed
+func Update(ds DSLer, m Cruder) (affected bool, err error) {
+	return UpdateContext(context.Background(), WithContext(ds), m)
+}
+
+// Update model row with context
+func UpdateContext(ctx context.Context, ds DSLerContext, m Cruder) (affected bool, err error) {
+	return save(ctx, OpUpdate, m, func(ctx context.Context) (affected bool, err error) {
+		affected, err = update(ctx, ds, fieldsOf(m))
+		if errors.Is(err, ErrNotFound) {
+			err = nil
+		}
+		return
+	})
+}
+
+// Insert model or update existing row with the same primary key.
+// Sequence columns are inserted when they are set
+func Upsert(ds DSLer, m Cruder) (affected bool, err error) {
+	return UpsertContext(context.Background(), WithContext(ds), m)
+}
+
+// Insert or update model with context
+func UpsertContext(ctx context.Context, ds DSLerContext, m Cruder) (affected bool, err error) {
+	return save(ctx, OpUpsert, m, func(ctx context.Context) (bool, error) {
+		return insertOnConflict(ctx, ds, fieldsOf(m))
+	})
+}
+
+// Run save operation inside instrumentation
+func save(ctx context.Context, op Operation, m Cruder, fn func(ctx context.Context) (bool, error)) (affected bool, err error) {
+	err = instrument(ctx, op, m, func(ctx context.Context) (err error) {
+		affected, err = fn(ctx)
+		return
+	})
+	return
+}
+
+func create(ctx context.Context, ds DSLerContext, f *fields) (affected bool, err error) {
+	if err = beforeSave(ctx, ds, f.m, true); err != nil {
+		return
+	}
+	if affected, err = insertRow(ctx, ds, f); err == nil {
+		err = saved(ctx, ds, f)
+	}
+	return
+}
+
+// Insert validated model and scan inserted row back
+func insertRow(ctx context.Context, ds DSLerContext, f *fields) (affected bool, err error) {
+	d := dialectOf(ds)
+	query, insertions := getSaveQuery(d, f)
+	qctx := withOperation(ctx, OpCreate, f.m)
+	if d.Returning() {
+		err = dbError(queryRowContext(qctx, ds, query, insertions...).Scan(f.scans()...))
+		affected = err == nil
+	} else {
+		affected, err = execReload(qctx, ds, f, query, insertions, true)
+	}
+	return
+}
+
+func update(ctx context.Context, ds DSLerContext, f *fields) (affected bool, err error) {
+	return updateColumns(ctx, ds, f, nil)
+}
+
+// Update given columns, nil columns means columns changed since last snapshot
+func updateColumns(ctx context.Context, ds DSLerContext, f *fields, columns []string) (affected bool, err error) {
+	m := f.m
+	if err = beforeSave(ctx, ds, m, false); err != nil {
+		return
+	}
+	if columns == nil {
+		columns = changedColumns(f)
+	}
+	if !anyUpdatable(m, columns) {
+		return
+	}
+	if columns, err = stampUpdate(m, columns); err != nil {
+		return
+	}
+	d := dialectOf(ds)
+	query, insertions := getPartialUpdateQuery(d, f, columns)
+	qctx := withOperation(ctx, OpUpdate, m)
+	if d.Returning() {
+		err = dbError(staleObject(m, queryRowContext(qctx, ds, query, insertions...).Scan(f.scans()...)))
+		affected = err == nil
+	} else {
+		affected, err = execReload(qctx, ds, f, query, insertions, false)
+	}
+	if err == nil {
+		err = saved(ctx, ds, f)
 	}
 	return
 }
 
-func update(ds DSLer, m Cruder) (err error) {
-	if err = m.Validate(); err == nil {
-		query, insertions := getUpdateQuery(m)
-		err = ds.QueryRow(query, insertions...).Scan(scans(m)...)
+func insertOnConflict(ctx context.Context, ds DSLerContext, f *fields) (affected bool, err error) {
+	if err = beforeSave(ctx, ds, f.m, true); err != nil {
+		return
+	}
+	if affected, err = upsertRow(ctx, ds, f); err == nil {
+		err = saved(ctx, ds, f)
 	}
 	return
 }
 
-func insertOnConflict(ds DSLer, m Cruder) (err error) {
-	if err = m.Validate(); err == nil {
-		query, insertions := getInsertOnConflictQuery(m)
-		err = ds.QueryRow(query, insertions...).Scan(scans(m)...)
+// Upsert validated model, versioned model with stale version fails with ErrStaleObject
+func upsertRow(ctx context.Context, ds DSLerContext, f *fields) (affected bool, err error) {
+	d := dialectOf(ds)
+	query, insertions := getInsertOnConflictQuery(d, f)
+	qctx := withOperation(ctx, OpUpsert, f.m)
+	if d.Returning() {
+		err = dbError(staleObject(f.m, queryRowContext(qctx, ds, query, insertions...).Scan(f.scans()...)))
+		affected = err == nil
+	} else {
+		affected, err = execReload(qctx, ds, f, query, insertions, true)
 	}
 	return
 }
 
+// Column can be assigned by update: version is incremented, creation time is kept
+// and deletion time is changed by Delete and Restore only
+func updatable(m Cruder, name string) bool {
+	if vname, _, ok := versionColumn(m); ok && vname == name {
+		return false
+	}
+	if cname, _, ok := createdAtColumn(m); ok && cname == name {
+		return false
+	}
+	if sd, ok := m.(SoftDeleter); ok {
+		if dname, _ := sd.SoftDeleteColumn(); dname == name {
+			return false
+		}
+	}
+	return true
+}
 
-func isUpdate(m Cruder) (ok bool) {
-	_, attrLink := m.Sequences()
-	if len(attrLink) == 0 {
+// Execute save query without RETURNING and read saved row back by primary key.
+// Inserted single sequence is taken from LastInsertId
+func execReload(ctx context.Context, ds DSLerContext, f *fields, query string, insertions []interface{}, insert bool) (affected bool, err error) {
+	result, err := execContext(ctx, ds, query, insertions...)
+	if err != nil {
+		err = dbError(err)
 		return
 	}
-	for _, value := range attrLink {
-		err := validation.Validate(value, validation.Required)
-		if err != nil {
-			ok = false
+	count, errAffected := result.RowsAffected()
+	affected = errAffected != nil || count > 0
+	if !insert {
+		if _, _, ok := versionColumn(f.m); ok && !affected {
+			err = ErrStaleObject
 			return
 		}
 	}
-	ok = true
+	err = reload(ctx, ds, f, result, insert)
 	return
 }
 
+// Read saved row back by primary key, inserted single sequence is taken from LastInsertId
+func reload(ctx context.Context, ds DSLerContext, f *fields, result sql.Result, insert bool) (err error) {
+	if insert && len(f.sequences) == 1 && !f.keyed() {
+		id, errId := result.LastInsertId()
+		if errId != nil {
+			return errId
+		}
+		if err = setValue(f.sequences[0], id); err != nil {
+			return
+		}
+	}
+	return dbError(queryRowContext(ctx, ds, getSelectQuery(f.m), f.primary...).Scan(f.scans()...))
+}
+
+func anyUpdatable(m Cruder, columns []string) bool {
+	for _, name := range metaOf(m).insertion {
+		if updatable(m, name) && existsInArrayString(name, columns) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUpdate(m Cruder) (ok bool) {
+	_, attrLink := m.Sequences()
+	return len(attrLink) > 0 && primaryExists(attrLink)
+}
+
 func primaryExists(input []interface{}) (ok bool) {
 	if input == nil {
 		return
@@ -0,0 +1,490 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Result of statement executed by test database
+type testResult struct {
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	lastId   int64
+	err      error
+}
+
+// In-memory database recording executed statements and returning queued results
+type testDB struct {
+	*sql.DB
+	mu       sync.Mutex
+	queries  []string
+	args     [][]interface{}
+	results  []testResult
+	prepared int             // prepared statements
+	closed   int             // closed prepared statements
+	ctx      context.Context // context of last statement
+}
+
+func newTestDB(t testing.TB) *testDB {
+	db := &testDB{}
+	db.DB = sql.OpenDB(testConnector{db})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Queue result of next statement
+func (db *testDB) push(results ...testResult) {
+	db.mu.Lock()
+	db.results = append(db.results, results...)
+	db.mu.Unlock()
+}
+
+// Executed statements with whitespace collapsed
+func (db *testDB) executed() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	queries := make([]string, len(db.queries))
+	for key, query := range db.queries {
+		queries[key] = oneLine(query)
+	}
+	return queries
+}
+
+func (db *testDB) next(query string, args []driver.NamedValue) testResult {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries = append(db.queries, query)
+	values := make([]interface{}, len(args))
+	for key, arg := range args {
+		values[key] = arg.Value
+		// attribute links are recorded by value at execution
+		if v := reflect.ValueOf(arg.Value); v.Kind() == reflect.Ptr && !v.IsNil() {
+			values[key] = v.Elem().Interface()
+		}
+	}
+	db.args = append(db.args, values)
+	if len(db.results) == 0 {
+		return testResult{}
+	}
+	result := db.results[0]
+	db.results = db.results[1:]
+	return result
+}
+
+// Remember context passed to driver
+func (db *testDB) received(ctx context.Context) {
+	db.mu.Lock()
+	db.ctx = ctx
+	db.mu.Unlock()
+}
+
+func (db *testDB) query(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	db.received(ctx)
+	result := db.next(query, args)
+	if result.err != nil {
+		return nil, result.err
+	}
+	return &testRows{result: result}, nil
+}
+
+func (db *testDB) exec(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	db.received(ctx)
+	result := db.next(query, args)
+	if result.err != nil {
+		return nil, result.err
+	}
+	return testExecResult(result), nil
+}
+
+type testConnector struct {
+	db *testDB
+}
+
+func (c testConnector) Connect(context.Context) (driver.Conn, error) {
+	return testConn(c), nil
+}
+
+func (c testConnector) Driver() driver.Driver {
+	return testDriver{}
+}
+
+type testDriver struct{}
+
+func (testDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("test database is opened by connector")
+}
+
+type testConn struct {
+	db *testDB
+}
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.mu.Lock()
+	c.db.prepared++
+	c.db.mu.Unlock()
+	return &testStmt{db: c.db, query: query}, nil
+}
+
+func (c testConn) Close() error {
+	return nil
+}
+
+func (c testConn) Begin() (driver.Tx, error) {
+	c.db.next("BEGIN", nil)
+	return testTx(c), nil
+}
+
+func (c testConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(ctx, query, args)
+}
+
+func (c testConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.db.exec(ctx, query, args)
+}
+
+// Pass arguments as is, e.g. attribute links
+func (c testConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+type testTx struct {
+	db *testDB
+}
+
+func (tx testTx) Commit() error {
+	return tx.db.next("COMMIT", nil).err
+}
+
+func (tx testTx) Rollback() error {
+	return tx.db.next("ROLLBACK", nil).err
+}
+
+type testStmt struct {
+	db    *testDB
+	query string
+}
+
+func (s *testStmt) Close() error {
+	s.db.mu.Lock()
+	s.db.closed++
+	s.db.mu.Unlock()
+	return nil
+}
+
+func (s *testStmt) NumInput() int {
+	return -1
+}
+
+func (s *testStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s *testStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s *testStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.db.exec(ctx, s.query, args)
+}
+
+func (s *testStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.db.query(ctx, s.query, args)
+}
+
+type testExecResult testResult
+
+func (r testExecResult) LastInsertId() (int64, error) {
+	return r.lastId, nil
+}
+
+func (r testExecResult) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
+
+type testRows struct {
+	result testResult
+	next   int
+}
+
+func (r *testRows) Columns() []string {
+	return r.result.columns
+}
+
+func (r *testRows) Close() error {
+	return nil
+}
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
+
+// Driver error with SQLSTATE
+type testStateError string
+
+func (e testStateError) Error() string {
+	return "SQLSTATE " + string(e)
+}
+
+func (e testStateError) SQLState() string {
+	return string(e)
+}
+
+// Model with sequence and a column named by reserved word
+type probe struct {
+	Id    int64
+	Name  string
+	Order int64
+}
+
+func (m *probe) Columns() ([]string, []interface{}) {
+	return []string{"name", "order"}, []interface{}{&m.Name, &m.Order}
+}
+
+func (m *probe) PrimaryKey() ([]string, []interface{}) {
+	return []string{"id"}, []interface{}{&m.Id}
+}
+
+func (m *probe) Sequences() ([]string, []interface{}) {
+	return []string{"id"}, []interface{}{&m.Id}
+}
+
+func (m *probe) TableName() string {
+	return "public.probe"
+}
+
+func (m *probe) Validate() error {
+	return nil
+}
+
+// Model with natural key
+type tag struct {
+	Code string
+	Name string
+}
+
+func (m *tag) Columns() ([]string, []interface{}) {
+	return []string{"name"}, []interface{}{&m.Name}
+}
+
+func (m *tag) PrimaryKey() ([]string, []interface{}) {
+	return []string{"code"}, []interface{}{&m.Code}
+}
+
+func (m *tag) Sequences() ([]string, []interface{}) {
+	return nil, nil
+}
+
+func (m *tag) TableName() string {
+	return "tag"
+}
+
+func (m *tag) Validate() error {
+	return nil
+}
+
+// Soft deleted, versioned and timestamped model
+type document struct {
+	Id        int64
+	Title     string
+	Version   int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+}
+
+func (m *document) Columns() ([]string, []interface{}) {
+	return []string{"title", "version", "created_at", "updated_at", "deleted_at"},
+		[]interface{}{&m.Title, &m.Version, &m.CreatedAt, &m.UpdatedAt, &m.DeletedAt}
+}
+
+func (m *document) PrimaryKey() ([]string, []interface{}) {
+	return []string{"id"}, []interface{}{&m.Id}
+}
+
+func (m *document) Sequences() ([]string, []interface{}) {
+	return []string{"id"}, []interface{}{&m.Id}
+}
+
+func (m *document) TableName() string {
+	return "document"
+}
+
+func (m *document) Validate() error {
+	return nil
+}
+
+func (m *document) VersionColumn() (string, interface{}) {
+	return "version", &m.Version
+}
+
+func (m *document) CreatedAtColumn() (string, interface{}) {
+	return "created_at", &m.CreatedAt
+}
+
+func (m *document) UpdatedAtColumn() (string, interface{}) {
+	return "updated_at", &m.UpdatedAt
+}
+
+func (m *document) SoftDeleteColumn() (string, interface{}) {
+	return "deleted_at", &m.DeletedAt
+}
+
+// Returned row of document with version and deletion time
+func documentRow(id int64, version int64, deletedAt interface{}) testResult {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return testResult{
+		columns: []string{"id", "title", "version", "created_at", "updated_at", "deleted_at"},
+		rows:    [][]driver.Value{{id, "a", version, created, created, deletedAt}},
+	}
+}
+
+// Statement with whitespace collapsed
+func oneLine(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// Model counting calls of Cruder methods
+type countingProbe struct {
+	probe
+	calls map[string]int
+}
+
+func (m *countingProbe) Columns() ([]string, []interface{}) {
+	m.calls["Columns"]++
+	return m.probe.Columns()
+}
+
+func (m *countingProbe) PrimaryKey() ([]string, []interface{}) {
+	m.calls["PrimaryKey"]++
+	return m.probe.PrimaryKey()
+}
+
+func (m *countingProbe) Sequences() ([]string, []interface{}) {
+	m.calls["Sequences"]++
+	return m.probe.Sequences()
+}
+
+func probeRow(id int64) testResult {
+	return testResult{columns: []string{"id", "name", "order"}, rows: [][]driver.Value{{id, "a", int64(1)}}}
+}
+
+func TestSaveTakesModelFieldsOnce(t *testing.T) {
+	db := newTestDB(t)
+	for _, id := range []int64{0, 7} {
+		m := &countingProbe{probe: probe{Id: id, Name: "a"}, calls: map[string]int{}}
+		Save(db, m) // type metadata
+		m.calls = map[string]int{}
+		db.push(probeRow(7))
+		if err := Save(db, m); err != nil {
+			t.Fatal(err)
+		}
+		for name, count := range m.calls {
+			if count > 1 {
+				t.Errorf("id %d: %s called %d times", id, name, count)
+			}
+		}
+	}
+}
+
+func BenchmarkSave(b *testing.B) {
+	db := newTestDB(b)
+	m := &probe{Id: 7, Name: "a"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		db.push(probeRow(7))
+		if err := Save(db, m); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Record spans and metrics of test
+func useRecorder(t testing.TB) *Recorder {
+	r := NewRecorder()
+	tracer, metrics := DefaultTracer, DefaultMetrics
+	DefaultTracer, DefaultMetrics = r, r
+	t.Cleanup(func() { DefaultTracer, DefaultMetrics = tracer, metrics })
+	return r
+}
+
+func TestInsert(t *testing.T) {
+	cases := []struct {
+		model *probe
+		want  string
+		args  int
+	}{
+		{&probe{Name: "a"}, `INSERT INTO "public"."probe" ("name","order") VALUES ( $1, $2) RETURNING "id", "name", "order";`, 2},
+		{&probe{Id: 5, Name: "a"}, `INSERT INTO "public"."probe" ("id","name","order") VALUES ( $1, $2, $3) RETURNING "id", "name", "order";`, 3},
+	}
+	for _, c := range cases {
+		db := newTestDB(t)
+		db.push(probeRow(5))
+		if affected, err := Insert(db, c.model); err != nil || !affected {
+			t.Fatalf("Insert = %v, %v", affected, err)
+		}
+		if queries := db.executed(); len(queries) != 1 || queries[0] != c.want || len(db.args[0]) != c.args {
+			t.Errorf("executed %q %v, want %s", queries, db.args, c.want)
+		}
+	}
+}
+
+func TestInsertDuplicateSequence(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{err: testStateError("23505")})
+	m := &probe{Id: 5, Name: "a"}
+	if affected, err := Insert(db, m); !errors.Is(err, ErrUniqueViolation) || affected {
+		t.Errorf("Insert = %v, %v, want ErrUniqueViolation", affected, err)
+	}
+	if m.Id != 5 {
+		t.Errorf("id %d, want 5", m.Id)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	r := useRecorder(t)
+	db := newTestDB(t)
+	db.push(testResult{columns: []string{"id", "name", "order"}})
+	if affected, err := Update(db, &probe{Id: 5, Name: "a"}); err != nil || affected {
+		t.Errorf("Update of missing row = %v, %v, want false, nil", affected, err)
+	}
+	if errs := r.Errors(OpUpdate, "public.probe", ""); errs != 0 {
+		t.Errorf("missing row counted as %d errors", errs)
+	}
+	if spans := r.Spans(); len(spans) != 1 || spans[0].Err != nil {
+		t.Errorf("spans %+v", spans)
+	}
+	db.push(probeRow(5))
+	if affected, err := Update(db, &probe{Id: 5, Name: "a"}); err != nil || !affected {
+		t.Errorf("Update = %v, %v", affected, err)
+	}
+	want := `UPDATE "public"."probe" SET "name" = $2, "order" = $3 WHERE "id" = $1 RETURNING "id", "name", "order";`
+	if queries := db.executed(); len(queries) != 2 || queries[1] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{columns: []string{"code", "name"}, rows: [][]driver.Value{{"a", "x"}}})
+	if affected, err := Upsert(db, &tag{Code: "a", Name: "x"}); err != nil || !affected {
+		t.Fatalf("Upsert = %v, %v", affected, err)
+	}
+	want := `INSERT INTO "tag" ("code","name") VALUES ( $1, $2) ON CONFLICT ("code") DO UPDATE SET "code" = EXCLUDED."code", "name" = EXCLUDED."name" RETURNING "code", "name" ;`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+}
@@ -0,0 +1,308 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQL dialect of database. Statements are cached per dialect, so implementation must be comparable
+type Dialect interface {
+	// Bind parameter with number n starting from 1
+	Placeholder(n int) string
+	// Quoted table or column name
+	Quote(identifier string) string
+	// Clause after VALUES of insert which updates existing row on conflict
+	Upsert(clause UpsertClause) string
+	// Support of RETURNING in INSERT and UPDATE, otherwise LastInsertId and reload by primary key are used
+	Returning() bool
+	// Row locking clause of select, empty if rows can not be locked
+	Lock(opts LockOptions) string
+}
+
+// Upsert clause parts
+type UpsertClause struct {
+	Conflict   []string // conflict target columns
+	Constraint string   // conflict target constraint, used instead of columns when set
+	Update     []string // columns assigned from inserted row
+	Set        []string // extra assignments, e.g. version increment
+	Where      string   // condition of update of existing row
+	DoNothing  bool     // keep existing row unchanged
+	// single sequence column, MySQL reports its value of updated row by LAST_INSERT_ID
+	Sequence string
+}
+
+// DSLer with dialect
+type Dialecter interface {
+	Dialect() Dialect
+}
+
+var (
+	Postgres Dialect = postgres{}
+	SQLite   Dialect = sqlite{}
+	MySQL    Dialect = mysql{}
+)
+
+// Dialect of DSLer without one
+var DefaultDialect = Postgres
+
+type postgres struct{}
+
+func (postgres) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgres) Quote(identifier string) string {
+	return `"` + identifier + `"`
+}
+
+func (postgres) Upsert(clause UpsertClause) string {
+	return onConflictUpsert(clause, "EXCLUDED")
+}
+
+func (postgres) Returning() bool {
+	return true
+}
+
+func (postgres) Lock(opts LockOptions) string {
+	return "FOR " + opts.Strength.String() + opts.Wait.clause()
+}
+
+type sqlite struct{}
+
+func (sqlite) Placeholder(n int) string {
+	return "?" + strconv.Itoa(n)
+}
+
+func (sqlite) Quote(identifier string) string {
+	return `"` + identifier + `"`
+}
+
+func (sqlite) Upsert(clause UpsertClause) string {
+	return onConflictUpsert(clause, "excluded")
+}
+
+func (sqlite) Returning() bool {
+	return true
+}
+
+// SQLite locks the whole database on write
+func (sqlite) Lock(opts LockOptions) string {
+	return ""
+}
+
+type mysql struct{}
+
+func (mysql) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysql) Quote(identifier string) string {
+	return "`" + identifier + "`"
+}
+
+// MySQL has no conditional upsert, so condition is applied to every assignment.
+// Conflict target is not supported, any unique key conflicts.
+// Sequence is assigned through LAST_INSERT_ID to read updated row back by it
+func (mysql) Upsert(clause UpsertClause) string {
+	if clause.DoNothing {
+		return "ON DUPLICATE KEY UPDATE " + clause.Conflict[0] + " = " + clause.Conflict[0]
+	}
+	assignments := make([]string, 0, len(clause.Update)+len(clause.Set))
+	for _, column := range clause.Update {
+		value := "VALUES(" + column + ")"
+		if clause.Where != "" {
+			value = "IF(" + clause.Where + ", " + value + ", " + column + ")"
+		}
+		assignments = append(assignments, column+" = "+value)
+	}
+	for _, set := range clause.Set {
+		if clause.Where != "" {
+			parts := strings.SplitN(set, " = ", 2)
+			set = parts[0] + " = IF(" + clause.Where + ", " + parts[1] + ", " + parts[0] + ")"
+		}
+		assignments = append(assignments, set)
+	}
+	if clause.Sequence != "" {
+		assignments = append(assignments, clause.Sequence+" = LAST_INSERT_ID("+clause.Sequence+")")
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+}
+
+func (mysql) Returning() bool {
+	return false
+}
+
+// MySQL has no key locks, they are replaced by stronger ones
+func (mysql) Lock(opts LockOptions) string {
+	strength := "UPDATE"
+	if opts.Strength == ForShare || opts.Strength == ForKeyShare {
+		strength = "SHARE"
+	}
+	return "FOR " + strength + opts.Wait.clause()
+}
+
+func onConflictUpsert(clause UpsertClause, excluded string) string {
+	target := "(" + strings.Join(clause.Conflict, ", ") + ")"
+	if clause.Constraint != "" {
+		target = "ON CONSTRAINT " + clause.Constraint
+	}
+	if clause.DoNothing {
+		return "ON CONFLICT " + target + " DO NOTHING"
+	}
+	assignments := make([]string, 0, len(clause.Update)+len(clause.Set))
+	for _, column := range clause.Update {
+		assignments = append(assignments, column+" = "+excluded+"."+column)
+	}
+	assignments = append(assignments, clause.Set...)
+	query := "ON CONFLICT " + target + " DO UPDATE SET " + strings.Join(assignments, ", ")
+	if clause.Where != "" {
+		query += " WHERE " + clause.Where
+	}
+	return query
+}
+
+// Dialect of DSLer or of wrapped DSLer, DefaultDialect if there is none
+func dialectOf(ds interface{}) Dialect {
+	for ds != nil {
+		if d, ok := ds.(Dialecter); ok {
+			return d.Dialect()
+		}
+		w, ok := ds.(dslerWrapper)
+		if !ok {
+			break
+		}
+		ds = w.unwrap()
+	}
+	return DefaultDialect
+}
+
+// DSLer wrapper which is reapplied to transactions started by WithTx
+type dslerWrapper interface {
+	unwrap() DSLer
+	rewrap(inner DSLer) DSLer
+}
+
+// Innermost DSLer and function wrapping a DSLer the same way
+func unwrapDSLer(ds DSLer) (DSLer, func(DSLer) DSLer) {
+	w, ok := ds.(dslerWrapper)
+	if !ok {
+		return ds, func(inner DSLer) DSLer { return inner }
+	}
+	inner, wrap := unwrapDSLer(w.unwrap())
+	return inner, func(tx DSLer) DSLer { return w.rewrap(wrap(tx)) }
+}
+
+// DSLer with dialect
+type dialectDSLer struct {
+	DSLerContext
+	ds      DSLer
+	dialect Dialect
+}
+
+// Use dialect for all crud queries of DSLer
+func WithDialect(ds DSLer, d Dialect) DSLer {
+	return &dialectDSLer{DSLerContext: WithContext(ds), ds: ds, dialect: d}
+}
+
+func (w *dialectDSLer) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return w.ds.Query(query, args...)
+}
+
+func (w *dialectDSLer) QueryRow(query string, args ...interface{}) *sql.Row {
+	return w.ds.QueryRow(query, args...)
+}
+
+func (w *dialectDSLer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return w.ds.Exec(query, args...)
+}
+
+func (w *dialectDSLer) Dialect() Dialect {
+	return w.dialect
+}
+
+func (w *dialectDSLer) unwrap() DSLer {
+	return w.ds
+}
+
+func (w *dialectDSLer) rewrap(inner DSLer) DSLer {
+	return WithDialect(inner, w.dialect)
+}
+
+// Convert numbered $n placeholders outside of quotes to dialect placeholders
+// and double quoted identifiers to dialect quotes.
+// Arguments are reordered to follow placeholders, so positional ? works as well
+func rebind(d Dialect, query string, args []interface{}) (string, []interface{}) {
+	if d == Postgres {
+		return query, args
+	}
+	var b strings.Builder
+	bound := make([]interface{}, 0, len(args))
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '\'' {
+			quoted = !quoted
+		}
+		if !quoted && c == '"' {
+			if end := strings.IndexByte(query[i+1:], '"'); end >= 0 {
+				b.WriteString(d.Quote(query[i+1 : i+1+end]))
+				i += end + 1
+				continue
+			}
+		}
+		j := i + 1
+		for !quoted && c == '$' && j < len(query) && query[j] >= '0' && query[j] <= '9' {
+			j++
+		}
+		if j == i+1 {
+			b.WriteByte(c)
+			continue
+		}
+		n, _ := strconv.Atoi(query[i+1 : j])
+		if n >= 1 && n <= len(args) {
+			bound = append(bound, args[n-1])
+		}
+		b.WriteString(d.Placeholder(len(bound)))
+		i = j - 1
+	}
+	return b.String(), bound
+}
+
+func queryContext(ctx context.Context, ds DSLerContext, query string, args ...interface{}) (*sql.Rows, error) {
+	query, args = rebind(dialectOf(ds), query, args)
+	o := observerOf(ds)
+	if o == nil {
+		return ds.QueryContext(ctx, query, args...)
+	}
+	start := time.Now()
+	rows, err := ds.QueryContext(ctx, query, args...)
+	observe(ctx, o, query, args, start, -1, err)
+	return rows, err
+}
+
+func queryRowContext(ctx context.Context, ds DSLerContext, query string, args ...interface{}) *sql.Row {
+	query, args = rebind(dialectOf(ds), query, args)
+	o := observerOf(ds)
+	if o == nil {
+		return ds.QueryRowContext(ctx, query, args...)
+	}
+	start := time.Now()
+	row := ds.QueryRowContext(ctx, query, args...)
+	observe(ctx, o, query, args, start, -1, row.Err())
+	return row
+}
+
+func execContext(ctx context.Context, ds DSLerContext, query string, args ...interface{}) (sql.Result, error) {
+	query, args = rebind(dialectOf(ds), query, args)
+	o := observerOf(ds)
+	if o == nil {
+		return ds.ExecContext(ctx, query, args...)
+	}
+	start := time.Now()
+	result, err := ds.ExecContext(ctx, query, args...)
+	observe(ctx, o, query, args, start, rowsAffected(result, err), err)
+	return result, err
+}
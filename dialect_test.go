@@ -0,0 +1,87 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		query   string
+		args    []interface{}
+		want    string
+		bound   []interface{}
+	}{
+		{Postgres, `SELECT "a" FROM "t" WHERE "a" = $2 AND "b" = $1`, []interface{}{1, 2},
+			`SELECT "a" FROM "t" WHERE "a" = $2 AND "b" = $1`, []interface{}{1, 2}},
+		{SQLite, `SELECT "a" FROM "t" WHERE "a" = $2 AND "b" = $1`, []interface{}{1, 2},
+			`SELECT "a" FROM "t" WHERE "a" = ?1 AND "b" = ?2`, []interface{}{2, 1}},
+		{MySQL, `SELECT "a" FROM "s"."t" WHERE "a" = $1 OR "a" = $1`, []interface{}{1},
+			"SELECT `a` FROM `s`.`t` WHERE `a` = ? OR `a` = ?", []interface{}{1, 1}},
+		{MySQL, `UPDATE "t" SET "a" = '$1 "b"' WHERE "c" = $1`, []interface{}{3},
+			"UPDATE `t` SET `a` = '$1 \"b\"' WHERE `c` = ?", []interface{}{3}},
+		{MySQL, `SELECT $ FROM "t"`, nil, "SELECT $ FROM `t`", []interface{}{}},
+	}
+	for _, c := range cases {
+		query, bound := rebind(c.dialect, c.query, c.args)
+		if query != c.want || !reflect.DeepEqual(bound, c.bound) {
+			t.Errorf("rebind(%s) = %s %v, want %s %v", c.query, query, bound, c.want, c.bound)
+		}
+	}
+}
+
+func TestDialectUpsert(t *testing.T) {
+	clause := UpsertClause{
+		Conflict: []string{`"id"`},
+		Update:   []string{`"name"`},
+		Set:      []string{`"version" = "t"."version" + 1`},
+		Where:    `"t"."version" = $3`,
+	}
+	cases := []struct {
+		dialect Dialect
+		clause  UpsertClause
+		want    string
+	}{
+		{Postgres, clause, `ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "version" = "t"."version" + 1 WHERE "t"."version" = $3`},
+		{SQLite, clause, `ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name", "version" = "t"."version" + 1 WHERE "t"."version" = $3`},
+		{MySQL, clause, `ON DUPLICATE KEY UPDATE "name" = IF("t"."version" = $3, VALUES("name"), "name"), "version" = IF("t"."version" = $3, "t"."version" + 1, "version")`},
+		{Postgres, UpsertClause{Constraint: `"uniq"`, DoNothing: true}, `ON CONFLICT ON CONSTRAINT "uniq" DO NOTHING`},
+		{MySQL, UpsertClause{Conflict: []string{`"id"`}, DoNothing: true}, `ON DUPLICATE KEY UPDATE "id" = "id"`},
+	}
+	for _, c := range cases {
+		if got := c.dialect.Upsert(c.clause); got != c.want {
+			t.Errorf("%T upsert = %s, want %s", c.dialect, got, c.want)
+		}
+	}
+}
+
+func TestDialectLock(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		opts    LockOptions
+		want    string
+	}{
+		{Postgres, LockOptions{}, "FOR UPDATE"},
+		{Postgres, LockOptions{Strength: ForNoKeyUpdate, Wait: NoWait}, "FOR NO KEY UPDATE NOWAIT"},
+		{Postgres, LockOptions{Strength: ForKeyShare, Wait: SkipLocked}, "FOR KEY SHARE SKIP LOCKED"},
+		{MySQL, LockOptions{Strength: ForNoKeyUpdate}, "FOR UPDATE"},
+		{MySQL, LockOptions{Strength: ForKeyShare, Wait: NoWait}, "FOR SHARE NOWAIT"},
+		{SQLite, LockOptions{}, ""},
+	}
+	for _, c := range cases {
+		if got := c.dialect.Lock(c.opts); got != c.want {
+			t.Errorf("%T lock %+v = %q, want %q", c.dialect, c.opts, got, c.want)
+		}
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	db := newTestDB(t)
+	if d := dialectOf(db); d != DefaultDialect {
+		t.Errorf("dialect of plain DSLer %T", d)
+	}
+	if d := dialectOf(WithDialect(db, MySQL)); d != MySQL {
+		t.Errorf("dialect of WithDialect %T", d)
+	}
+}
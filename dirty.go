@@ -0,0 +1,112 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// Model which keeps snapshot of column values after load and save
+type DirtyTracker interface {
+	Snapshot() map[string]interface{}
+	SetSnapshot(values map[string]interface{})
+}
+
+// Embeddable snapshot storage implementing DirtyTracker
+type Tracker struct {
+	snapshot map[string]interface{}
+}
+
+// Column values at the moment of last load or save
+func (t *Tracker) Snapshot() map[string]interface{} {
+	return t.snapshot
+}
+
+// Replace column values snapshot
+func (t *Tracker) SetSnapshot(values map[string]interface{}) {
+	t.snapshot = values
+}
+
+// Remember current column values of DirtyTracker model
+func TakeSnapshot(m Cruder) {
+	takeSnapshot(fieldsOf(m))
+}
+
+func takeSnapshot(f *fields) {
+	tracker, ok := f.m.(DirtyTracker)
+	if !ok {
+		return
+	}
+	names, links := f.insertion()
+	values := make(map[string]interface{}, len(names))
+	for key, name := range names {
+		values[name] = copyValue(reflect.ValueOf(links[key]).Elem())
+	}
+	tracker.SetSnapshot(values)
+}
+
+// Columns changed since last snapshot, all insertion columns if there is no snapshot
+func ChangedColumns(m Cruder) (names []string) {
+	return append([]string{}, changedColumns(fieldsOf(m))...)
+}
+
+// Changed columns sharing names of type metadata when all columns are changed
+func changedColumns(f *fields) (names []string) {
+	all, links := f.insertion()
+	var snapshot map[string]interface{}
+	if tracker, ok := f.m.(DirtyTracker); ok {
+		snapshot = tracker.Snapshot()
+	}
+	if snapshot == nil {
+		return all
+	}
+	names = make([]string, 0)
+	for key, name := range all {
+		value, ok := snapshot[name]
+		if !ok || !reflect.DeepEqual(value, copyValue(reflect.ValueOf(links[key]).Elem())) {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// Copy value so later changes through pointers or slices do not affect it
+func copyValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v.Interface()
+		}
+		cp := reflect.New(v.Elem().Type())
+		cp.Elem().Set(reflect.ValueOf(copyValue(v.Elem())))
+		return cp.Interface()
+	case reflect.Slice:
+		if v.IsNil() {
+			return v.Interface()
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(cp, v)
+		return cp.Interface()
+	}
+	return v.Interface()
+}
+
+// Update only given columns of model
+func UpdateColumns(ds DSLer, m Cruder, columns ...string) error {
+	return UpdateColumnsContext(context.Background(), WithContext(ds), m, columns...)
+}
+
+// Update only given columns of model with context
+func UpdateColumnsContext(ctx context.Context, ds DSLerContext, m Cruder, columns ...string) (err error) {
+	if columns == nil {
+		columns = []string{}
+	}
+	for _, column := range columns {
+		if !existsInArrayString(column, metaOf(m).insertion) {
+			err = errors.New("unknown column for update: " + column)
+			return
+		}
+	}
+	_, err = updateColumns(ctx, ds, fieldsOf(m), columns)
+	return
+}
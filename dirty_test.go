@@ -0,0 +1,94 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Model tracking changed columns
+type trackedProbe struct {
+	probe
+	Tracker
+}
+
+func TestChangedColumnsAfterLoad(t *testing.T) {
+	db := newTestDB(t)
+	m := &trackedProbe{probe: probe{Id: 5}}
+	if names := ChangedColumns(m); !reflect.DeepEqual(names, []string{"name", "order"}) {
+		t.Errorf("changed without snapshot %v", names)
+	}
+	db.push(probeRow(5))
+	if _, err := Load(db, m); err != nil {
+		t.Fatal(err)
+	}
+	if names := ChangedColumns(m); len(names) != 0 {
+		t.Errorf("changed after Load %v", names)
+	}
+	m.Order = 2
+	if names := ChangedColumns(m); !reflect.DeepEqual(names, []string{"order"}) {
+		t.Errorf("changed %v, want [order]", names)
+	}
+}
+
+func TestSaveUpdatesChangedColumns(t *testing.T) {
+	db := newTestDB(t)
+	m := &trackedProbe{probe: probe{Id: 5}}
+	db.push(probeRow(5), probeRow(5))
+	if _, err := Load(db, m); err != nil {
+		t.Fatal(err)
+	}
+	m.Name = "b"
+	if err := Save(db, m); err != nil {
+		t.Fatal(err)
+	}
+	want := `UPDATE "public"."probe" SET "name" = $2 WHERE "id" = $1 RETURNING "id", "name", "order";`
+	if queries := db.executed(); len(queries) != 2 || queries[1] != want {
+		t.Fatalf("executed %q, want %s", queries, want)
+	}
+	// saved row is the new snapshot
+	if names := ChangedColumns(m); len(names) != 0 {
+		t.Errorf("changed after Save %v", names)
+	}
+	if err := Save(db, m); err != nil {
+		t.Fatal(err)
+	}
+	if queries := db.executed(); len(queries) != 2 {
+		t.Errorf("unchanged model is updated: %q", queries)
+	}
+}
+
+func TestUpdateColumns(t *testing.T) {
+	db := newTestDB(t)
+	db.push(probeRow(5))
+	m := &trackedProbe{probe: probe{Id: 5, Name: "b", Order: 3}}
+	if err := UpdateColumns(db, m, "order"); err != nil {
+		t.Fatal(err)
+	}
+	want := `UPDATE "public"."probe" SET "order" = $2 WHERE "id" = $1 RETURNING "id", "name", "order";`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+	if names := ChangedColumns(m); len(names) != 0 {
+		t.Errorf("changed after UpdateColumns %v", names)
+	}
+	if err := UpdateColumns(db, m, "missing"); err == nil {
+		t.Error("unknown column is updated")
+	}
+	if err := UpdateColumns(db, m); err != nil || len(db.executed()) != 1 {
+		t.Errorf("UpdateColumns without columns = %v, executed %q", err, db.executed())
+	}
+}
+
+func TestSnapshotCopiesValues(t *testing.T) {
+	name := "a"
+	link := &name
+	tags := []string{"a"}
+	if cp := copyValue(reflect.ValueOf(link)).(*string); cp == link || *cp != "a" {
+		t.Errorf("pointer is not copied")
+	}
+	cp := copyValue(reflect.ValueOf(tags)).([]string)
+	tags[0] = "b"
+	if cp[0] != "a" {
+		t.Errorf("slice is not copied")
+	}
+}
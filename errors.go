@@ -0,0 +1,101 @@
+package crud
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrNotFound            = errors.New("record not found")
+	ErrNoPrimaryKey        = errors.New("no primary key specified")
+	ErrValidation          = errors.New("validation failed")
+	ErrUniqueViolation     = errors.New("unique violation")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrNotNullViolation    = errors.New("not null violation")
+	ErrCheckViolation      = errors.New("check violation")
+	// serialization failure or deadlock, transaction may be retried
+	ErrSerialization    = errors.New("serialization failure")
+	ErrStaleObject      = errors.New("stale object: row was changed or deleted by another transaction")
+	ErrNotSoftDeleter   = errors.New("model does not support soft delete")
+	ErrCopyNotSupported = errors.New("DSLer does not support COPY")
+	// table or column name containing quote characters
+	ErrInvalidIdentifier = errors.New("invalid identifier")
+	ErrUnknownRelation   = errors.New("unknown relation")
+	// bulk update or delete without filter conditions
+	ErrFullTable             = errors.New("empty filter would affect every row of table")
+	ErrReturningNotSupported = errors.New("dialect does not support RETURNING")
+	// row is locked by another transaction and lock was requested with NOWAIT
+	ErrLockNotAvailable = errors.New("lock not available")
+	// query built by NewQuery is rendered without model, use Select
+	ErrNoModel = errors.New("query has no model")
+)
+
+// Postgres SQLSTATE codes mapped onto crud errors
+var sqlStateErrors = map[string]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23502": ErrNotNullViolation,
+	"23514": ErrCheckViolation,
+	"40001": ErrSerialization,
+	"40P01": ErrSerialization,
+	"55P03": ErrLockNotAvailable,
+}
+
+// Error of crud operation. errors.Is matches Kind, errors.As reaches driver error through Err
+type Error struct {
+	Kind error  // crud sentinel error
+	Code string // SQLSTATE if known
+	Err  error  // underlying error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Driver error with SQLSTATE, e.g. *pq.Error or *pgconn.PgError
+type sqlStater interface {
+	SQLState() string
+}
+
+// SQLSTATE of error or empty string
+func SQLState(err error) string {
+	var state sqlStater
+	if errors.As(err, &state) {
+		return state.SQLState()
+	}
+	return ""
+}
+
+// Map driver error onto crud error
+func dbError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	if err == sql.ErrNoRows {
+		return &Error{Kind: ErrNotFound, Err: err}
+	}
+	code := SQLState(err)
+	if kind, ok := sqlStateErrors[code]; ok {
+		return &Error{Kind: kind, Code: code, Err: err}
+	}
+	return err
+}
+
+func validate(m Cruder) error {
+	if err := m.Validate(); err != nil {
+		return &Error{Kind: ErrValidation, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package crud
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+// Model failing validation
+type invalidProbe struct {
+	probe
+}
+
+func (m *invalidProbe) Validate() error {
+	return errors.New("name is required")
+}
+
+func TestDBErrorKinds(t *testing.T) {
+	cases := []struct {
+		code string
+		kind error
+	}{
+		{"23505", ErrUniqueViolation},
+		{"23503", ErrForeignKeyViolation},
+		{"23502", ErrNotNullViolation},
+		{"23514", ErrCheckViolation},
+		{"40001", ErrSerialization},
+		{"40P01", ErrSerialization},
+		{"55P03", ErrLockNotAvailable},
+	}
+	for _, c := range cases {
+		db := newTestDB(t)
+		db.push(testResult{err: testStateError(c.code)})
+		err := Save(db, &probe{Name: "a"})
+		if !errors.Is(err, c.kind) {
+			t.Errorf("%s: error %v, want %v", c.code, err, c.kind)
+		}
+		var e *Error
+		if !errors.As(err, &e) || e.Code != c.code || e.Kind != c.kind {
+			t.Errorf("%s: error %#v", c.code, err)
+		}
+		var driverErr testStateError
+		if !errors.As(err, &driverErr) || string(driverErr) != c.code || SQLState(err) != c.code {
+			t.Errorf("%s: driver error is not reachable from %v", c.code, err)
+		}
+	}
+}
+
+func TestDBErrorPassesUnknown(t *testing.T) {
+	unknown := testStateError("42P01")
+	if err := dbError(unknown); err != unknown {
+		t.Errorf("dbError(%v) = %v", unknown, err)
+	}
+	plain := errors.New("connection lost")
+	if err := dbError(plain); err != plain || SQLState(err) != "" {
+		t.Errorf("dbError(%v) = %v", plain, err)
+	}
+	if err := dbError(sql.ErrNoRows); !errors.Is(err, ErrNotFound) || !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("dbError(ErrNoRows) = %v", err)
+	}
+	mapped := dbError(testStateError("23505"))
+	if err := dbError(mapped); err != mapped {
+		t.Errorf("mapped error is wrapped again: %v", err)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	db := newTestDB(t)
+	err := Save(db, &invalidProbe{})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("error %v, want ErrValidation", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || errors.Unwrap(err).Error() != "name is required" {
+		t.Errorf("validation error %#v", err)
+	}
+	if queries := db.executed(); len(queries) != 0 {
+		t.Errorf("invalid model is saved: %q", queries)
+	}
+}
@@ -0,0 +1,141 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Pointer to model type T
+type Model[T any] interface {
+	*T
+	Cruder
+}
+
+// Find models by filter
+func Find[T any, PT Model[T]](ds DSLer, filter Filter) ([]T, error) {
+	return FindContext[T, PT](context.Background(), WithContext(ds), filter)
+}
+
+// Find models by filter with context
+func FindContext[T any, PT Model[T]](ctx context.Context, ds DSLerContext, filter Filter) (result []T, err error) {
+	err = instrument(ctx, OpSearch, PT(new(T)), func(ctx context.Context) (err error) {
+		if err = checkSearch(PT(new(T)), filter); err != nil {
+			return
+		}
+		query := SearchQuery(PT(new(T)), filter) + lockClause(dialectOf(ds), filter)
+		result, err = find[T, PT](ctx, ds, query, Arguments(filter))
+		return
+	})
+	return
+}
+
+// Run query and scan rows into models, extra destinations are scanned after model columns
+func find[T any, PT Model[T]](ctx context.Context, ds DSLerContext, query string, args []interface{}, extra ...interface{}) (result []T, err error) {
+	return scanModels[T, PT](ctx, ds, OpSearch, query, args, extra...)
+}
+
+// Run query of operation and scan returned rows into models
+func scanModels[T any, PT Model[T]](ctx context.Context, ds DSLerContext, op Operation, query string, args []interface{}, extra ...interface{}) (result []T, err error) {
+	m := PT(new(T))
+	if err = checkIdentifiers(m); err != nil {
+		return
+	}
+	rows, err := queryContext(withOperation(ctx, op, m), ds, query, args...)
+	if err != nil {
+		err = dbError(err)
+		return
+	}
+	defer rows.Close()
+	result = make([]T, 0)
+	for rows.Next() {
+		var row T
+		if err = rows.Scan(append(scans(PT(&row)), extra...)...); err != nil {
+			return
+		}
+		result = append(result, row)
+	}
+	if err = dbError(rows.Err()); err != nil {
+		return
+	}
+	rows.Close()
+	for key := range result {
+		if err = Loaded(ctx, ds, PT(&result[key])); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// First model by filter, ErrNotFound if nothing matches
+func First[T any, PT Model[T]](ds DSLer, filter Filter) (*T, error) {
+	return FirstContext[T, PT](context.Background(), WithContext(ds), filter)
+}
+
+// First model by filter with context
+func FirstContext[T any, PT Model[T]](ctx context.Context, ds DSLerContext, filter Filter) (*T, error) {
+	if q, ok := filter.(*Query); ok {
+		cp := *q
+		filter = cp.Limit(1)
+	}
+	result, err := FindContext[T, PT](ctx, ds, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, ErrNotFound
+	}
+	return &result[0], nil
+}
+
+// Get model by primary key values in order of PrimaryKey, ErrNotFound if there is no such row
+func Get[T any, PT Model[T]](ds DSLer, pk ...interface{}) (*T, error) {
+	return GetContext[T, PT](context.Background(), WithContext(ds), pk...)
+}
+
+// Get model by primary key values with context
+func GetContext[T any, PT Model[T]](ctx context.Context, ds DSLerContext, pk ...interface{}) (*T, error) {
+	m := PT(new(T))
+	_, links := m.PrimaryKey()
+	if len(links) == 0 || len(links) != len(pk) {
+		return nil, ErrNoPrimaryKey
+	}
+	for key, link := range links {
+		if err := setValue(link, pk[key]); err != nil {
+			return nil, err
+		}
+	}
+	find, err := LoadContext(ctx, ds, m)
+	if err != nil {
+		return nil, err
+	}
+	if !find {
+		return nil, ErrNotFound
+	}
+	return (*T)(m), nil
+}
+
+// Assign value to attribute link converting compatible types.
+// Integer is not converted to string, Go would make a one rune string of it
+func setValue(link interface{}, value interface{}) error {
+	dst := reflect.ValueOf(link).Elem()
+	src := reflect.ValueOf(value)
+	if !src.IsValid() {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if !src.Type().ConvertibleTo(dst.Type()) || isInteger(src.Kind()) && dst.Kind() == reflect.String {
+		return fmt.Errorf("can not assign %T to %s", value, dst.Type())
+	}
+	dst.Set(src.Convert(dst.Type()))
+	return nil
+}
+
+func isInteger(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
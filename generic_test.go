@@ -0,0 +1,31 @@
+package crud
+
+import "testing"
+
+func TestSetValue(t *testing.T) {
+	var id int64
+	if err := setValue(&id, int32(7)); err != nil || id != 7 {
+		t.Errorf("int64 = %d, %v", id, err)
+	}
+	var code string
+	if err := setValue(&code, 65); err == nil {
+		t.Errorf("integer is converted to string %q", code)
+	}
+	if err := setValue(&code, []byte("A")); err != nil || code != "A" {
+		t.Errorf("string = %q, %v", code, err)
+	}
+	deleted := &id
+	if err := setValue(&deleted, nil); err != nil || deleted != nil {
+		t.Errorf("pointer = %v, %v", deleted, err)
+	}
+}
+
+func TestGetRejectsIntegerOfStringKey(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := Get[tag](db, 65); err == nil {
+		t.Error("integer key of string column is accepted")
+	}
+	if queries := db.executed(); len(queries) != 0 {
+		t.Errorf("executed %q", queries)
+	}
+}
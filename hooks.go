@@ -0,0 +1,96 @@
+package crud
+
+import (
+	"context"
+)
+
+// Hook called before insert and upsert, prior to validation
+type BeforeCreate interface {
+	BeforeCreate(ctx context.Context, ds DSLerContext) error
+}
+
+// Hook called before update, prior to validation
+type BeforeUpdate interface {
+	BeforeUpdate(ctx context.Context, ds DSLerContext) error
+}
+
+// Hook called after successful insert, update or upsert
+type AfterSave interface {
+	AfterSave(ctx context.Context, ds DSLerContext) error
+}
+
+// Hook called before soft or hard delete
+type BeforeDelete interface {
+	BeforeDelete(ctx context.Context, ds DSLerContext) error
+}
+
+// Hook called after successful delete
+type AfterDelete interface {
+	AfterDelete(ctx context.Context, ds DSLerContext) error
+}
+
+// Hook called after model is scanned from database
+type AfterLoad interface {
+	AfterLoad(ctx context.Context, ds DSLerContext) error
+}
+
+// Check identifiers, stamp creation time, run create or update hook and validate model
+func beforeSave(ctx context.Context, ds DSLerContext, m Cruder, creating bool) (err error) {
+	if err = checkIdentifiers(m); err != nil {
+		return
+	}
+	if creating {
+		if err = stampCreate(m); err != nil {
+			return
+		}
+	}
+	if h, ok := m.(BeforeCreate); ok && creating {
+		err = h.BeforeCreate(ctx, ds)
+	} else if h, ok := m.(BeforeUpdate); ok && !creating {
+		err = h.BeforeUpdate(ctx, ds)
+	}
+	if err != nil {
+		return
+	}
+	err = validate(m)
+	return
+}
+
+// Finish saving of model: take snapshot and call AfterSave hook
+func saved(ctx context.Context, ds DSLerContext, f *fields) error {
+	takeSnapshot(f)
+	if h, ok := f.m.(AfterSave); ok {
+		return h.AfterSave(ctx, ds)
+	}
+	return nil
+}
+
+// Finish loading of scanned model: take snapshot and call AfterLoad hook
+func Loaded(ctx context.Context, ds DSLerContext, m Cruder) error {
+	TakeSnapshot(m)
+	if h, ok := m.(AfterLoad); ok {
+		return h.AfterLoad(ctx, ds)
+	}
+	return nil
+}
+
+// Run delete between BeforeDelete and AfterDelete hooks
+func deleteWithHooks(ctx context.Context, ds DSLerContext, m Cruder, del func(ctx context.Context) error) error {
+	return instrument(ctx, OpDelete, m, func(ctx context.Context) (err error) {
+		if err = checkIdentifiers(m); err != nil {
+			return
+		}
+		if h, ok := m.(BeforeDelete); ok {
+			if err = h.BeforeDelete(ctx, ds); err != nil {
+				return
+			}
+		}
+		if err = del(ctx); err != nil {
+			return
+		}
+		if h, ok := m.(AfterDelete); ok {
+			err = h.AfterDelete(ctx, ds)
+		}
+		return
+	})
+}
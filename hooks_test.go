@@ -0,0 +1,133 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Model recording calls of hooks, hook named by fail returns error
+type hookedProbe struct {
+	probe
+	calls []string
+	fail  string
+}
+
+func (m *hookedProbe) hook(name string) error {
+	m.calls = append(m.calls, name)
+	if name == m.fail {
+		return errors.New(name + " failed")
+	}
+	return nil
+}
+
+func (m *hookedProbe) Validate() error {
+	return m.hook("Validate")
+}
+
+func (m *hookedProbe) BeforeCreate(ctx context.Context, ds DSLerContext) error {
+	return m.hook("BeforeCreate")
+}
+
+func (m *hookedProbe) BeforeUpdate(ctx context.Context, ds DSLerContext) error {
+	return m.hook("BeforeUpdate")
+}
+
+func (m *hookedProbe) AfterSave(ctx context.Context, ds DSLerContext) error {
+	return m.hook("AfterSave")
+}
+
+func (m *hookedProbe) BeforeDelete(ctx context.Context, ds DSLerContext) error {
+	return m.hook("BeforeDelete")
+}
+
+func (m *hookedProbe) AfterDelete(ctx context.Context, ds DSLerContext) error {
+	return m.hook("AfterDelete")
+}
+
+func (m *hookedProbe) AfterLoad(ctx context.Context, ds DSLerContext) error {
+	return m.hook("AfterLoad")
+}
+
+func TestHookOrder(t *testing.T) {
+	cases := []struct {
+		name  string
+		id    int64
+		run   func(db *testDB, m *hookedProbe) error
+		calls []string
+	}{
+		{"create", 0, func(db *testDB, m *hookedProbe) error { return Save(db, m) }, []string{"BeforeCreate", "Validate", "AfterSave"}},
+		{"update", 5, func(db *testDB, m *hookedProbe) error { return Save(db, m) }, []string{"BeforeUpdate", "Validate", "AfterSave"}},
+		{"delete", 5, func(db *testDB, m *hookedProbe) error { return Delete(db, m) }, []string{"BeforeDelete", "AfterDelete"}},
+		{"load", 5, func(db *testDB, m *hookedProbe) error { _, err := Load(db, m); return err }, []string{"AfterLoad"}},
+	}
+	for _, c := range cases {
+		db := newTestDB(t)
+		db.push(probeRow(5))
+		m := &hookedProbe{probe: probe{Id: c.id, Name: "a"}}
+		if err := c.run(db, m); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(m.calls, c.calls) {
+			t.Errorf("%s: hooks %v, want %v", c.name, m.calls, c.calls)
+		}
+		if queries := db.executed(); len(queries) != 1 {
+			t.Errorf("%s: executed %q", c.name, queries)
+		}
+	}
+}
+
+func TestHookErrorStopsStatement(t *testing.T) {
+	cases := []struct {
+		fail string
+		id   int64
+		run  func(db *testDB, m *hookedProbe) error
+	}{
+		{"BeforeCreate", 0, func(db *testDB, m *hookedProbe) error { return Save(db, m) }},
+		{"BeforeUpdate", 5, func(db *testDB, m *hookedProbe) error { return Save(db, m) }},
+		{"Validate", 0, func(db *testDB, m *hookedProbe) error { return Save(db, m) }},
+		{"BeforeDelete", 5, func(db *testDB, m *hookedProbe) error { return Delete(db, m) }},
+	}
+	for _, c := range cases {
+		db := newTestDB(t)
+		m := &hookedProbe{probe: probe{Id: c.id, Name: "a"}, fail: c.fail}
+		if err := c.run(db, m); err == nil {
+			t.Errorf("%s: error is not returned", c.fail)
+		}
+		if queries := db.executed(); len(queries) != 0 {
+			t.Errorf("%s: executed %q", c.fail, queries)
+		}
+		if last := m.calls[len(m.calls)-1]; last != c.fail {
+			t.Errorf("%s: hooks %v called after failed hook", c.fail, m.calls)
+		}
+	}
+}
+
+func TestAfterHooksSkippedOnFailure(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{err: testStateError("23505")}, testResult{err: testStateError("23503")})
+	m := &hookedProbe{probe: probe{Name: "a"}}
+	if err := Save(db, m); !errors.Is(err, ErrUniqueViolation) {
+		t.Errorf("Save error %v", err)
+	}
+	m.Id = 5
+	if err := Delete(db, m); !errors.Is(err, ErrForeignKeyViolation) {
+		t.Errorf("Delete error %v", err)
+	}
+	if want := []string{"BeforeCreate", "Validate", "BeforeDelete"}; !reflect.DeepEqual(m.calls, want) {
+		t.Errorf("hooks %v, want %v", m.calls, want)
+	}
+}
+
+func TestAfterHookError(t *testing.T) {
+	db := newTestDB(t)
+	db.push(probeRow(5))
+	m := &hookedProbe{probe: probe{Name: "a"}, fail: "AfterSave"}
+	if err := Save(db, m); err == nil || err.Error() != "AfterSave failed" {
+		t.Errorf("error %v, want AfterSave failed", err)
+	}
+	if m.Id != 5 {
+		t.Errorf("id %d, want 5", m.Id)
+	}
+}
@@ -0,0 +1,81 @@
+package crud
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Characters which can not be a part of quoted identifier
+const identifierQuotes = "\"`'"
+
+// Quoted table or column name, parts of schema qualified name are quoted separately.
+// Double quotes are converted to quotes of dialect on execution
+func quoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for key, part := range parts {
+		parts[key] = `"` + part + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+func quoteIdents(names []string) []string {
+	quoted := make([]string, len(names))
+	for key, name := range names {
+		quoted[key] = quoteIdent(name)
+	}
+	return quoted
+}
+
+// Check that identifier can be quoted safely
+func checkIdentifier(name string) error {
+	if name == "" || strings.ContainsAny(name, identifierQuotes) {
+		return fmt.Errorf("%w: %q", ErrInvalidIdentifier, name)
+	}
+	return nil
+}
+
+// Check table and column names of model, result is cached per model type
+func checkIdentifiers(m Cruder) error {
+	return metaOf(m).err
+}
+
+// Check names of model and columns of Query filter
+func checkSearch(m Cruder, filter Filter) error {
+	if err := checkIdentifiers(m); err != nil {
+		return err
+	}
+	filter, _ = fullTable(filter)
+	if q, ok := unscope(filter).(*Query); ok {
+		return q.err
+	}
+	return nil
+}
+
+func identifiersError(m Cruder) error {
+	names := []string{m.TableName()}
+	primary, _ := m.PrimaryKey()
+	names = append(names, primary...)
+	columns, _ := m.Columns()
+	names = append(names, columns...)
+	sequences, _ := m.Sequences()
+	names = append(names, sequences...)
+	if sd, ok := m.(SoftDeleter); ok {
+		name, _ := sd.SoftDeleteColumn()
+		names = append(names, name)
+	}
+	if name, _, ok := versionColumn(m); ok {
+		names = append(names, name)
+	}
+	if name, _, ok := createdAtColumn(m); ok {
+		names = append(names, name)
+	}
+	if name, _, ok := updatedAtColumn(m); ok {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		if err := checkIdentifier(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package crud
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuoteIdent(t *testing.T) {
+	cases := map[string]string{
+		"id":           `"id"`,
+		"order":        `"order"`,
+		"public.probe": `"public"."probe"`,
+	}
+	for name, want := range cases {
+		if got := quoteIdent(name); got != want {
+			t.Errorf("quoteIdent(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestCheckIdentifier(t *testing.T) {
+	for _, name := range []string{"id", "public.probe", "order"} {
+		if err := checkIdentifier(name); err != nil {
+			t.Errorf("checkIdentifier(%s) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", `a"b`, "a`b", "a'b"} {
+		if err := checkIdentifier(name); !errors.Is(err, ErrInvalidIdentifier) {
+			t.Errorf("checkIdentifier(%q) = %v, want ErrInvalidIdentifier", name, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package crud
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Span attribute
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Tracer of crud operations, e.g. adapter of OpenTelemetry tracer
+type Tracer interface {
+	StartSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span of crud operation
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	End(err error)
+}
+
+// Metrics of crud operations, e.g. adapter of Prometheus collectors
+type Metrics interface {
+	// Latency of operation for histogram
+	ObserveLatency(op Operation, table string, latency time.Duration)
+	// Failed operation, code is SQLSTATE of error or empty string
+	IncError(op Operation, table string, code string)
+}
+
+// Tracer of Load, Save, Delete and Find, nil disables tracing
+var DefaultTracer Tracer
+
+// Metrics of Load, Save, Delete and Find, nil disables metrics
+var DefaultMetrics Metrics
+
+// Run operation on model inside span and record its metrics
+func instrument(ctx context.Context, op Operation, m Cruder, fn func(ctx context.Context) error) error {
+	tracer, metrics := DefaultTracer, DefaultMetrics
+	if tracer == nil && metrics == nil {
+		return fn(ctx)
+	}
+	table := m.TableName()
+	start := time.Now()
+	var span Span
+	if tracer != nil {
+		ctx, span = tracer.StartSpan(ctx, "crud."+string(op),
+			Attribute{Key: "db.table", Value: table},
+			Attribute{Key: "db.operation", Value: string(op)})
+	}
+	err := fn(ctx)
+	if span != nil {
+		if code := SQLState(err); code != "" {
+			span.SetAttributes(Attribute{Key: "db.sqlstate", Value: code})
+		}
+		span.End(err)
+	}
+	if metrics != nil {
+		metrics.ObserveLatency(op, table, time.Since(start))
+		if err != nil {
+			metrics.IncError(op, table, SQLState(err))
+		}
+	}
+	return err
+}
+
+// Finished or running span of Recorder
+type RecordedSpan struct {
+	Name       string
+	Attributes []Attribute
+	Err        error
+	Start      time.Time
+	End        time.Time // zero while span is running
+}
+
+// In-memory Tracer and Metrics for tests
+type Recorder struct {
+	mu        sync.Mutex
+	spans     []*RecordedSpan
+	latencies map[string][]time.Duration
+	errors    map[string]int
+}
+
+// New empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{latencies: map[string][]time.Duration{}, errors: map[string]int{}}
+}
+
+type recorderSpan struct {
+	r    *Recorder
+	span *RecordedSpan
+}
+
+func (r *Recorder) StartSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	span := &RecordedSpan{Name: name, Attributes: append([]Attribute{}, attrs...), Start: time.Now()}
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+	return ctx, recorderSpan{r: r, span: span}
+}
+
+func (s recorderSpan) SetAttributes(attrs ...Attribute) {
+	s.r.mu.Lock()
+	s.span.Attributes = append(s.span.Attributes, attrs...)
+	s.r.mu.Unlock()
+}
+
+func (s recorderSpan) End(err error) {
+	s.r.mu.Lock()
+	s.span.Err = err
+	s.span.End = time.Now()
+	s.r.mu.Unlock()
+}
+
+func (r *Recorder) ObserveLatency(op Operation, table string, latency time.Duration) {
+	r.mu.Lock()
+	key := string(op) + " " + table
+	r.latencies[key] = append(r.latencies[key], latency)
+	r.mu.Unlock()
+}
+
+func (r *Recorder) IncError(op Operation, table string, code string) {
+	r.mu.Lock()
+	r.errors[string(op)+" "+table+" "+code]++
+	r.mu.Unlock()
+}
+
+// Recorded spans in order of start
+func (r *Recorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]RecordedSpan, len(r.spans))
+	for key, span := range r.spans {
+		spans[key] = *span
+		spans[key].Attributes = append([]Attribute{}, span.Attributes...)
+	}
+	return spans
+}
+
+// Recorded latencies of operation on table
+func (r *Recorder) Latencies(op Operation, table string) []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Duration{}, r.latencies[string(op)+" "+table]...)
+}
+
+// Count of errors of operation on table with SQLSTATE code, empty code counts errors without SQLSTATE
+func (r *Recorder) Errors(op Operation, table string, code string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.errors[string(op)+" "+table+" "+code]
+}
+
+// Drop recorded spans and metrics
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.spans = nil
+	r.latencies = map[string][]time.Duration{}
+	r.errors = map[string]int{}
+	r.mu.Unlock()
+}
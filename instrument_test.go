@@ -0,0 +1,101 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestInstrumentOperations(t *testing.T) {
+	cases := []struct {
+		name  string
+		op    Operation
+		run   func(db *testDB) error
+		state string
+	}{
+		{"create", OpCreate, func(db *testDB) error { return Save(db, &probe{Name: "a"}) }, ""},
+		{"update", OpUpdate, func(db *testDB) error { return Save(db, &probe{Id: 5, Name: "a"}) }, ""},
+		{"upsert", OpUpsert, func(db *testDB) error { return Save(db, &tag{Code: "a"}) }, ""},
+		{"delete", OpDelete, func(db *testDB) error { return Delete(db, &probe{Id: 5}) }, ""},
+		{"load", OpLoad, func(db *testDB) error { _, err := Load(db, &probe{Id: 5}); return err }, ""},
+		{"unique", OpCreate, func(db *testDB) error { return Save(db, &probe{Name: "a"}) }, "23505"},
+		{"foreign key", OpDelete, func(db *testDB) error { return Delete(db, &probe{Id: 5}) }, "23503"},
+		{"driver", OpLoad, func(db *testDB) error { _, err := Load(db, &probe{Id: 5}); return err }, "-"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := useRecorder(t)
+			db := newTestDB(t)
+			table := "public.probe"
+			if c.op == OpUpsert {
+				table = "tag"
+			}
+			switch {
+			case c.op == OpUpsert:
+				db.push(testResult{columns: []string{"code", "name"}, rows: [][]driver.Value{{"a", ""}}})
+			case c.state == "":
+				db.push(probeRow(5))
+			case c.state == "-":
+				db.push(testResult{err: errors.New("connection lost")})
+			default:
+				db.push(testResult{err: testStateError(c.state)})
+			}
+			err := c.run(db)
+			if (err != nil) != (c.state != "") {
+				t.Fatalf("error %v", err)
+			}
+			spans := r.Spans()
+			if len(spans) != 1 || spans[0].Name != "crud."+string(c.op) || spans[0].End.IsZero() || spans[0].Err != err {
+				t.Fatalf("spans %+v", spans)
+			}
+			want := []Attribute{{Key: "db.table", Value: table}, {Key: "db.operation", Value: string(c.op)}}
+			if c.state != "" && c.state != "-" {
+				want = append(want, Attribute{Key: "db.sqlstate", Value: c.state})
+			}
+			if attrs := spans[0].Attributes; len(attrs) != len(want) {
+				t.Errorf("attributes %v, want %v", attrs, want)
+			} else {
+				for key := range want {
+					if attrs[key] != want[key] {
+						t.Errorf("attributes %v, want %v", attrs, want)
+					}
+				}
+			}
+			if latencies := r.Latencies(c.op, table); len(latencies) != 1 {
+				t.Errorf("latencies %v", latencies)
+			}
+			code := c.state
+			if code == "-" {
+				code = ""
+			}
+			errs := r.Errors(c.op, table, code)
+			if (c.state == "" && errs != 0) || (c.state != "" && errs != 1) {
+				t.Errorf("%d errors with code %q", errs, code)
+			}
+		})
+	}
+}
+
+func TestInstrumentDisabled(t *testing.T) {
+	r := useRecorder(t)
+	DefaultTracer, DefaultMetrics = nil, nil
+	db := newTestDB(t)
+	db.push(probeRow(5))
+	if _, err := Load(db, &probe{Id: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if spans := r.Spans(); len(spans) != 0 {
+		t.Errorf("spans %+v", spans)
+	}
+}
+
+func TestRecorderReset(t *testing.T) {
+	r := useRecorder(t)
+	db := newTestDB(t)
+	db.push(testResult{err: testStateError("23505")})
+	Save(db, &probe{Name: "a"})
+	r.Reset()
+	if len(r.Spans()) != 0 || len(r.Latencies(OpCreate, "public.probe")) != 0 || r.Errors(OpCreate, "public.probe", "23505") != 0 {
+		t.Errorf("records left after Reset")
+	}
+}
@@ -0,0 +1,99 @@
+package crud
+
+import (
+	"context"
+	"strings"
+)
+
+// Strength of row lock
+type LockStrength int
+
+const (
+	ForUpdate LockStrength = iota
+	// lock which does not block inserts of rows referencing locked one
+	ForNoKeyUpdate
+	ForShare
+	ForKeyShare
+)
+
+func (s LockStrength) String() string {
+	switch s {
+	case ForNoKeyUpdate:
+		return "NO KEY UPDATE"
+	case ForShare:
+		return "SHARE"
+	case ForKeyShare:
+		return "KEY SHARE"
+	}
+	return "UPDATE"
+}
+
+// Behaviour on rows locked by another transaction
+type LockWait int
+
+const (
+	Wait LockWait = iota
+	// fail with ErrLockNotAvailable
+	NoWait
+	// leave locked rows out of result, e.g. to take jobs from queue
+	SkipLocked
+)
+
+func (w LockWait) clause() string {
+	switch w {
+	case NoWait:
+		return " NOWAIT"
+	case SkipLocked:
+		return " SKIP LOCKED"
+	}
+	return ""
+}
+
+// Row locking of select, zero value is FOR UPDATE waiting for other transactions.
+// Locks are held until the end of transaction, so they make sense in WithTx only
+type LockOptions struct {
+	Strength LockStrength
+	Wait     LockWait
+}
+
+// Lock rows selected by query
+func (q *Query) Lock(opts LockOptions) *Query {
+	q.lock = &opts
+	return q
+}
+
+// Lock rows selected by query FOR UPDATE
+func (q *Query) ForUpdate() *Query {
+	return q.Lock(LockOptions{})
+}
+
+// Lock rows selected by query FOR SHARE
+func (q *Query) ForShare() *Query {
+	return q.Lock(LockOptions{Strength: ForShare})
+}
+
+// Locking clause of filter in dialect with leading space, empty if filter does not lock
+func lockClause(d Dialect, filter Filter) string {
+	q, ok := unscope(filter).(*Query)
+	if !ok || q.lock == nil {
+		return ""
+	}
+	if clause := d.Lock(*q.lock); clause != "" {
+		return " " + clause
+	}
+	return ""
+}
+
+// Load model and lock its row until the end of transaction
+func LoadForUpdate(tx DSLer, m Cruder, opts LockOptions) (find bool, err error) {
+	return LoadForUpdateContext(context.Background(), WithContext(tx), m, opts)
+}
+
+// Load and lock model with context
+func LoadForUpdateContext(ctx context.Context, tx DSLerContext, m Cruder, opts LockOptions) (find bool, err error) {
+	query := strings.TrimSuffix(GetLoadQuery(m), " ;")
+	if clause := dialectOf(tx).Lock(opts); clause != "" {
+		query += " " + clause
+	}
+	return load(ctx, tx, m, query+" ;")
+}
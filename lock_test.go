@@ -0,0 +1,35 @@
+package crud
+
+import "testing"
+
+func TestLockClause(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		filter  Filter
+		want    string
+	}{
+		{Postgres, nil, ""},
+		{Postgres, NewQuery(), ""},
+		{Postgres, NewQuery().ForUpdate(), " FOR UPDATE"},
+		{Postgres, WithDeleted(NewQuery().ForShare()), " FOR SHARE"},
+		{SQLite, NewQuery().ForUpdate(), ""},
+	}
+	for _, c := range cases {
+		if got := lockClause(c.dialect, c.filter); got != c.want {
+			t.Errorf("lockClause(%v) = %q, want %q", c.filter, got, c.want)
+		}
+	}
+}
+
+func TestLoadForUpdate(t *testing.T) {
+	db := newTestDB(t)
+	db.push(probeRow(7))
+	m := &probe{Id: 7}
+	if find, err := LoadForUpdate(db, m, LockOptions{Wait: NoWait}); err != nil || !find {
+		t.Fatalf("LoadForUpdate = %v, %v", find, err)
+	}
+	want := `SELECT "id", "name", "order" FROM "public"."probe" WHERE "id" = $1 FOR UPDATE NOWAIT ;`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+}
@@ -0,0 +1,147 @@
+package crud
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Precomputed names and SQL of model type. Table and column names must be the same for all models of a type
+type modelMeta struct {
+	err        error    // identifiers check result
+	list       string   // select list
+	primary    []string // primary key names
+	columns    []string // column names
+	sequences  []string // sequence names
+	sequenced  []bool   // primary key column is a sequence
+	insertion  []string // primary key columns without sequences followed by columns
+	statements sync.Map // Dialect -> *statements
+}
+
+// Statements of model type in dialect
+type statements struct {
+	load        string
+	selectByPk  string
+	delete      string
+	insert      string
+	insertKeyed string
+	upsert      string
+	upsertKeyed string
+	softDelete  string
+	restore     string
+	updates     sync.Map // updated columns -> update statement
+}
+
+// Model type -> *modelMeta
+var metas sync.Map
+
+func metaOf(m Cruder) *modelMeta {
+	t := reflect.TypeOf(m)
+	if meta, ok := metas.Load(t); ok {
+		return meta.(*modelMeta)
+	}
+	meta := &modelMeta{err: identifiersError(m), list: selectList(m)}
+	meta.primary, _ = m.PrimaryKey()
+	meta.columns, _ = m.Columns()
+	meta.sequences, _ = m.Sequences()
+	meta.sequenced = make([]bool, len(meta.primary))
+	for key, name := range meta.primary {
+		meta.sequenced[key] = existsInArrayString(name, meta.sequences)
+		if !meta.sequenced[key] {
+			meta.insertion = append(meta.insertion, name)
+		}
+	}
+	meta.insertion = append(meta.insertion, meta.columns...)
+	// appending to shared names must copy them
+	meta.insertion = meta.insertion[:len(meta.insertion):len(meta.insertion)]
+	actual, _ := metas.LoadOrStore(t, meta)
+	return actual.(*modelMeta)
+}
+
+// Model with attribute links taken once per operation, names come from type metadata
+type fields struct {
+	*modelMeta
+	m         Cruder
+	primary   []interface{}
+	columns   []interface{}
+	sequences []interface{}
+}
+
+func fieldsOf(m Cruder) *fields {
+	f := &fields{modelMeta: metaOf(m), m: m}
+	_, f.primary = m.PrimaryKey()
+	_, f.columns = m.Columns()
+	_, f.sequences = m.Sequences()
+	return f
+}
+
+// Insertion columns with attribute links
+func (f *fields) insertion() (names []string, attributeLinks []interface{}) {
+	attributeLinks = make([]interface{}, 0, len(f.modelMeta.insertion))
+	for key, link := range f.primary {
+		if !f.sequenced[key] {
+			attributeLinks = append(attributeLinks, link)
+		}
+	}
+	return f.modelMeta.insertion, append(attributeLinks, f.columns...)
+}
+
+// Inserted columns of upsert, keyed upsert inserts set sequences as well
+func (f *fields) upsertion(keyed bool) (names []string, attributeLinks []interface{}) {
+	if !keyed {
+		return f.insertion()
+	}
+	names = make([]string, 0, len(f.modelMeta.primary)+len(f.modelMeta.columns))
+	names = append(append(names, f.modelMeta.primary...), f.modelMeta.columns...)
+	attributeLinks = make([]interface{}, 0, len(names))
+	attributeLinks = append(append(attributeLinks, f.primary...), f.columns...)
+	return
+}
+
+// Scan destinations in order of select list
+func (f *fields) scans() []interface{} {
+	values := make([]interface{}, 0, len(f.primary)+len(f.columns)+1)
+	values = append(append(values, f.primary...), f.columns...)
+	if _, link, ok := systemVersion(f.m); ok {
+		values = append(values, link)
+	}
+	return values
+}
+
+// All sequences are set, so model row exists
+func (f *fields) keyed() bool {
+	return primaryExists(f.sequences) && len(f.sequences) > 0
+}
+
+func statementsOf(d Dialect, m Cruder) *statements {
+	meta := metaOf(m)
+	if s, ok := meta.statements.Load(d); ok {
+		return s.(*statements)
+	}
+	s := &statements{
+		load:        buildLoadQuery(m),
+		selectByPk:  buildSelectQuery(m),
+		delete:      buildDeleteQuery(m),
+		insert:      buildSaveQuery(d, m, false),
+		insertKeyed: buildSaveQuery(d, m, true),
+		upsert:      buildInsertOnConflictQuery(d, m, false, nil),
+		upsertKeyed: buildInsertOnConflictQuery(d, m, true, nil),
+	}
+	if sd, ok := m.(SoftDeleter); ok {
+		name, _ := sd.SoftDeleteColumn()
+		s.softDelete = buildSoftDeleteQuery(d, m, name)
+		s.restore = buildRestoreQuery(d, m, name)
+	}
+	actual, _ := meta.statements.LoadOrStore(d, s)
+	return actual.(*statements)
+}
+
+// Update statement of given columns
+func (s *statements) update(d Dialect, m Cruder, columns []string) string {
+	key := strings.Join(columns, ",")
+	if query, ok := s.updates.Load(key); ok {
+		return query.(string)
+	}
+	query, _ := s.updates.LoadOrStore(key, buildUpdateQuery(d, m, columns))
+	return query.(string)
+}
@@ -0,0 +1,60 @@
+package crud
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMetaOfIsSharedByType(t *testing.T) {
+	var wg sync.WaitGroup
+	metas := make([]*modelMeta, 8)
+	for key := range metas {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+			metas[key] = metaOf(&probe{Id: int64(key)})
+		}(key)
+	}
+	wg.Wait()
+	for _, meta := range metas {
+		if meta != metas[0] {
+			t.Fatal("type metadata is built more than once")
+		}
+	}
+	meta := metas[0]
+	if !reflect.DeepEqual(meta.insertion, []string{"name", "order"}) || !reflect.DeepEqual(meta.sequenced, []bool{true}) {
+		t.Errorf("metadata %+v", meta)
+	}
+	if metaOf(&tag{}) == meta {
+		t.Error("metadata is shared by different types")
+	}
+}
+
+func TestInsertionNamesAreNotShared(t *testing.T) {
+	names, _ := fieldsOf(&tag{}).insertion()
+	extended := append(names, "extra")
+	again, _ := fieldsOf(&tag{}).insertion()
+	if !reflect.DeepEqual(again, []string{"code", "name"}) || &extended[0] == &again[0] {
+		t.Errorf("insertion names %v changed by append", again)
+	}
+}
+
+func TestStatementsOfDialect(t *testing.T) {
+	m := &probe{}
+	postgres, mysql := statementsOf(Postgres, m), statementsOf(MySQL, m)
+	if statementsOf(Postgres, m) != postgres || postgres == mysql {
+		t.Fatal("statements are not cached per dialect")
+	}
+	if !strings.Contains(postgres.insert, "RETURNING") || strings.Contains(mysql.insert, "RETURNING") {
+		t.Errorf("insert statements %q and %q", postgres.insert, mysql.insert)
+	}
+	update := postgres.update(Postgres, m, []string{"name"})
+	if update != postgres.update(Postgres, m, []string{"name"}) || update == postgres.update(Postgres, m, []string{"name", "order"}) {
+		t.Errorf("update statements are not cached per columns")
+	}
+	if _, ok := postgres.updates.Load("name"); !ok {
+		t.Error("update of name is not cached")
+	}
+}
@@ -0,0 +1,205 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"reflect"
+	"time"
+)
+
+// Kind of crud operation
+type Operation string
+
+const (
+	OpLoad   Operation = "load"
+	OpCreate Operation = "create"
+	OpUpdate Operation = "update"
+	OpUpsert Operation = "upsert"
+	OpDelete Operation = "delete"
+	OpSearch Operation = "search"
+)
+
+// Executed query
+type QueryEvent struct {
+	Operation    Operation
+	Table        string
+	SQL          string
+	Args         []interface{} // argument values after RedactArguments
+	Duration     time.Duration
+	RowsAffected int64 // -1 for statements returning rows
+	Err          error
+}
+
+// Observer of queries executed by crud
+type QueryObserver interface {
+	ObserveQuery(ctx context.Context, event QueryEvent)
+}
+
+// Function implementing QueryObserver
+type QueryObserverFunc func(ctx context.Context, event QueryEvent)
+
+func (f QueryObserverFunc) ObserveQuery(ctx context.Context, event QueryEvent) {
+	f(ctx, event)
+}
+
+// Observer of DSLer without own observer, nil disables observation
+var Observer QueryObserver
+
+// Replace query arguments passed to observers, e.g. to hide passwords. Nil passes arguments as is
+var RedactArguments func(table string, query string, args []interface{}) []interface{}
+
+// DSLer with own observer
+type observable interface {
+	queryObserver() QueryObserver
+}
+
+// Operation of query in context
+type operation struct {
+	kind  Operation
+	table string
+}
+
+type operationKey struct{}
+
+// Mark queries executed with context as operation on model table
+func withOperation(ctx context.Context, kind Operation, m Cruder) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation{kind: kind, table: m.TableName()})
+}
+
+// Observer of DSLer or of wrapped DSLer, Observer if there is none
+func observerOf(ds interface{}) QueryObserver {
+	for ds != nil {
+		if o, ok := ds.(observable); ok {
+			return o.queryObserver()
+		}
+		w, ok := ds.(dslerWrapper)
+		if !ok {
+			break
+		}
+		ds = w.unwrap()
+	}
+	return Observer
+}
+
+func observe(ctx context.Context, o QueryObserver, query string, args []interface{}, start time.Time, affected int64, err error) {
+	op, _ := ctx.Value(operationKey{}).(operation)
+	values := make([]interface{}, len(args))
+	for key, arg := range args {
+		values[key] = argumentValue(arg)
+	}
+	if RedactArguments != nil {
+		values = RedactArguments(op.table, query, values)
+	}
+	o.ObserveQuery(ctx, QueryEvent{
+		Operation:    op.kind,
+		Table:        op.table,
+		SQL:          query,
+		Args:         values,
+		Duration:     time.Since(start),
+		RowsAffected: affected,
+		Err:          err,
+	})
+}
+
+// Value of attribute link, nil for nil pointer
+func argumentValue(arg interface{}) interface{} {
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func rowsAffected(result sql.Result, err error) int64 {
+	if err != nil {
+		return 0
+	}
+	affected, errAffected := result.RowsAffected()
+	if errAffected != nil {
+		return -1
+	}
+	return affected
+}
+
+// DSLer with observer
+type observedDSLer struct {
+	DSLerContext
+	ds       DSLer
+	observer QueryObserver
+}
+
+// Report crud queries of DSLer to observer instead of global Observer
+func WithObserver(ds DSLer, o QueryObserver) DSLer {
+	return &observedDSLer{DSLerContext: WithContext(ds), ds: ds, observer: o}
+}
+
+func (w *observedDSLer) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return w.ds.Query(query, args...)
+}
+
+func (w *observedDSLer) QueryRow(query string, args ...interface{}) *sql.Row {
+	return w.ds.QueryRow(query, args...)
+}
+
+func (w *observedDSLer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return w.ds.Exec(query, args...)
+}
+
+func (w *observedDSLer) queryObserver() QueryObserver {
+	return w.observer
+}
+
+func (w *observedDSLer) unwrap() DSLer {
+	return w.ds
+}
+
+func (w *observedDSLer) rewrap(inner DSLer) DSLer {
+	return WithObserver(inner, w.observer)
+}
+
+// QueryObserver writing queries to slog logger: failed queries with error level,
+// queries slower than SlowThreshold with warning level and others with debug level
+type SlogObserver struct {
+	Logger        *slog.Logger  // slog.Default() if nil
+	SlowThreshold time.Duration // zero disables slow query warnings
+}
+
+// New slog observer with slow query threshold
+func NewSlogObserver(logger *slog.Logger, slowThreshold time.Duration) *SlogObserver {
+	return &SlogObserver{Logger: logger, SlowThreshold: slowThreshold}
+}
+
+func (o *SlogObserver) ObserveQuery(ctx context.Context, event QueryEvent) {
+	logger := o.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	level, msg := slog.LevelDebug, "crud query"
+	if event.Err != nil {
+		level, msg = slog.LevelError, "crud query failed"
+	} else if o.SlowThreshold > 0 && event.Duration >= o.SlowThreshold {
+		level, msg = slog.LevelWarn, "crud slow query"
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("operation", string(event.Operation)),
+		slog.String("table", event.Table),
+		slog.String("sql", event.SQL),
+		slog.Any("args", event.Args),
+		slog.Duration("duration", event.Duration),
+		slog.Int64("rows_affected", event.RowsAffected),
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.String("error", event.Err.Error()))
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
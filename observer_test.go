@@ -0,0 +1,177 @@
+package crud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Observer collecting events
+type testObserver struct {
+	events []QueryEvent
+}
+
+func (o *testObserver) ObserveQuery(ctx context.Context, event QueryEvent) {
+	o.events = append(o.events, event)
+}
+
+func TestObserverEvents(t *testing.T) {
+	db := newTestDB(t)
+	o := &testObserver{}
+	ds := WithObserver(db, o)
+	db.push(probeRow(5), testResult{affected: 1})
+	if err := Save(ds, &probe{Name: "a", Order: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Delete(ds, &probe{Id: 5}); err != nil {
+		t.Fatal(err)
+	}
+	want := []QueryEvent{
+		{Operation: OpCreate, Table: "public.probe", Args: []interface{}{"a", int64(2)}, RowsAffected: -1},
+		{Operation: OpDelete, Table: "public.probe", Args: []interface{}{int64(5)}, RowsAffected: 1},
+	}
+	if len(o.events) != len(want) {
+		t.Fatalf("events %+v", o.events)
+	}
+	queries := db.executed()
+	for key, event := range o.events {
+		if event.Operation != want[key].Operation || event.Table != want[key].Table ||
+			!reflect.DeepEqual(event.Args, want[key].Args) || event.RowsAffected != want[key].RowsAffected {
+			t.Errorf("event %+v, want %+v", event, want[key])
+		}
+		if oneLine(event.SQL) != queries[key] || event.Err != nil {
+			t.Errorf("event of %s: %+v", queries[key], event)
+		}
+	}
+}
+
+func TestObserverError(t *testing.T) {
+	db := newTestDB(t)
+	o := &testObserver{}
+	db.push(testResult{err: testStateError("23505")})
+	if err := Save(WithObserver(db, o), &probe{Name: "a"}); !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("error %v", err)
+	}
+	if len(o.events) != 1 || SQLState(o.events[0].Err) != "23505" {
+		t.Errorf("events %+v", o.events)
+	}
+}
+
+func TestDefaultObserver(t *testing.T) {
+	o := &testObserver{}
+	observer := Observer
+	Observer = o
+	t.Cleanup(func() { Observer = observer })
+	db := newTestDB(t)
+	db.push(probeRow(5))
+	if _, err := Load(db, &probe{Id: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if len(o.events) != 1 || o.events[0].Operation != OpLoad || o.events[0].Table != "public.probe" {
+		t.Errorf("events %+v", o.events)
+	}
+	own := &testObserver{}
+	db.push(probeRow(5))
+	if _, err := Load(WithDialect(WithObserver(db, own), Postgres), &probe{Id: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if len(o.events) != 1 || len(own.events) != 1 {
+		t.Errorf("events of Observer %d, of wrapped observer %d", len(o.events), len(own.events))
+	}
+}
+
+func TestRedactArguments(t *testing.T) {
+	redact := RedactArguments
+	t.Cleanup(func() { RedactArguments = redact })
+	var tables, queries []string
+	RedactArguments = func(table string, query string, args []interface{}) []interface{} {
+		tables, queries = append(tables, table), append(queries, query)
+		args[0] = "***"
+		return args
+	}
+	db := newTestDB(t)
+	o := &testObserver{}
+	db.push(probeRow(5))
+	m := &probe{Name: "secret"}
+	if err := Save(WithObserver(db, o), m); err != nil {
+		t.Fatal(err)
+	}
+	if len(o.events) != 1 || !reflect.DeepEqual(o.events[0].Args, []interface{}{"***", int64(0)}) {
+		t.Errorf("events %+v", o.events)
+	}
+	if len(tables) != 1 || tables[0] != "public.probe" || queries[0] != o.events[0].SQL {
+		t.Errorf("redacted %v %v", tables, queries)
+	}
+	if got := db.args[0][0]; got != "secret" {
+		t.Errorf("executed with %v", got)
+	}
+}
+
+func TestArgumentValue(t *testing.T) {
+	var missing *string
+	name := "a"
+	link := &name
+	cases := []struct {
+		arg  interface{}
+		want interface{}
+	}{
+		{nil, nil},
+		{missing, nil},
+		{&name, "a"},
+		{&link, "a"},
+		{int64(1), int64(1)},
+	}
+	for _, c := range cases {
+		if got := argumentValue(c.arg); got != c.want {
+			t.Errorf("argumentValue(%v) = %v, want %v", c.arg, got, c.want)
+		}
+	}
+}
+
+func TestSlogObserver(t *testing.T) {
+	cases := []struct {
+		event QueryEvent
+		level string
+		msg   string
+	}{
+		{QueryEvent{Duration: time.Millisecond}, "DEBUG", "crud query"},
+		{QueryEvent{Duration: time.Second}, "WARN", "crud slow query"},
+		{QueryEvent{Duration: time.Millisecond, Err: errors.New("failed")}, "ERROR", "crud query failed"},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		o := NewSlogObserver(slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})), 100*time.Millisecond)
+		c.event.Operation, c.event.Table, c.event.SQL = OpUpdate, "public.probe", "UPDATE probe"
+		c.event.Args, c.event.RowsAffected = []interface{}{"a"}, 1
+		o.ObserveQuery(context.Background(), c.event)
+		var record map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+			t.Fatalf("%q: %v", out.String(), err)
+		}
+		want := map[string]interface{}{
+			"level": c.level, "msg": c.msg, "operation": "update", "table": "public.probe",
+			"sql": "UPDATE probe", "args": []interface{}{"a"}, "duration": float64(c.event.Duration), "rows_affected": float64(1),
+		}
+		if c.event.Err != nil {
+			want["error"] = "failed"
+		}
+		delete(record, "time")
+		if !reflect.DeepEqual(record, want) {
+			t.Errorf("logged %v, want %v", record, want)
+		}
+	}
+}
+
+func TestSlogObserverLevel(t *testing.T) {
+	var out bytes.Buffer
+	o := NewSlogObserver(slog.New(slog.NewTextHandler(&out, nil)), 0)
+	o.ObserveQuery(context.Background(), QueryEvent{Duration: time.Hour})
+	if out.Len() != 0 {
+		t.Errorf("debug query logged at info level: %s", out.String())
+	}
+}
@@ -0,0 +1,231 @@
+package crud
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Page size used when Page.Limit is not set
+const defaultPageLimit = 20
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Postgres does not allow window functions with FOR UPDATE and other locks
+var ErrLockedTotal = errors.New("offset page total can not be counted with row lock")
+
+// Pagination request. Keyset pagination is used when Keys are set, offset pagination otherwise
+type Page struct {
+	Limit  int      // page size
+	Offset int      // rows to skip, offset pagination only
+	Total  bool     // count all rows matching filter, with COUNT(*) OVER() in offset pagination, which rejects locks
+	Keys   []string // unique not null column set of keyset order, "-" prefix means descending, e.g. "-created_at", "-id"
+	Cursor string   // Next or Prev token of previous page, keyset pagination only
+}
+
+// Page of models
+type PageResult[T any] struct {
+	Items []T
+	Total int64  // count of all matching rows if requested
+	Next  string // keyset cursor of next page, empty on last page
+	Prev  string // keyset cursor of previous page, empty on first page
+}
+
+// Keyset cursor content
+type cursor struct {
+	Backward bool          `json:"b,omitempty"`
+	Values   []interface{} `json:"v"`
+}
+
+// Get page of models matched by query
+func Paginate[T any, PT Model[T]](ds DSLer, q *Query, page Page) (PageResult[T], error) {
+	return PaginateContext[T, PT](context.Background(), WithContext(ds), q, page)
+}
+
+// Get page of models matched by query with context. Rows are locked as query requests
+func PaginateContext[T any, PT Model[T]](ctx context.Context, ds DSLerContext, q *Query, page Page) (result PageResult[T], err error) {
+	if q == nil {
+		q = NewQuery()
+	}
+	if err = checkSearch(PT(new(T)), q); err != nil {
+		return
+	}
+	if page.Limit <= 0 {
+		page.Limit = defaultPageLimit
+	}
+	if len(page.Keys) > 0 {
+		return keysetPage[T, PT](ctx, ds, q, page)
+	}
+	return offsetPage[T, PT](ctx, ds, q, page)
+}
+
+func offsetPage[T any, PT Model[T]](ctx context.Context, ds DSLerContext, q *Query, page Page) (result PageResult[T], err error) {
+	m := PT(new(T))
+	cp := q.clone().Limit(page.Limit).Offset(page.Offset)
+	list := columnNames(m)
+	lock := lockClause(dialectOf(ds), cp)
+	var extra []interface{}
+	if page.Total {
+		if lock != "" {
+			err = ErrLockedTotal
+			return
+		}
+		list += ", COUNT(*) OVER()"
+		extra = append(extra, &result.Total)
+	}
+	items, err := find[T, PT](ctx, ds, selectQuery(m, list, cp)+lock, Arguments(cp), extra...)
+	if err != nil {
+		return
+	}
+	if page.Total && len(items) == 0 && page.Offset > 0 {
+		if result.Total, err = CountContext(ctx, ds, m, q); err != nil {
+			return
+		}
+	}
+	result.Items = items
+	return
+}
+
+func keysetPage[T any, PT Model[T]](ctx context.Context, ds DSLerContext, q *Query, page Page) (result PageResult[T], err error) {
+	var cur cursor
+	if page.Cursor != "" {
+		if cur, err = decodeCursor(page.Cursor); err != nil {
+			return
+		}
+		if len(cur.Values) != len(page.Keys) {
+			err = ErrInvalidCursor
+			return
+		}
+	}
+	columns := make([]string, len(page.Keys))
+	desc := make([]bool, len(page.Keys))
+	orders := make([]string, len(page.Keys))
+	for key, name := range page.Keys {
+		columns[key] = strings.TrimPrefix(name, "-")
+		if err = checkIdentifier(columns[key]); err != nil {
+			return
+		}
+		desc[key] = strings.HasPrefix(name, "-") != cur.Backward
+		orders[key] = quoteIdent(columns[key])
+		if desc[key] {
+			orders[key] += " DESC"
+		}
+	}
+	cp := q.clone().Limit(page.Limit + 1).Offset(0)
+	cp.orders = orders
+	if page.Cursor != "" {
+		sql, args := keysetCondition(columns, desc, cur.Values)
+		cp.scope(sql, args...)
+	}
+	query := SearchQuery(PT(new(T)), cp) + lockClause(dialectOf(ds), cp)
+	items, err := find[T, PT](ctx, ds, query, Arguments(cp))
+	if err != nil {
+		return
+	}
+	more := len(items) > page.Limit
+	if more {
+		items = items[:page.Limit]
+	}
+	if cur.Backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	if len(items) > 0 {
+		if more || cur.Backward {
+			if result.Next, err = encodeCursor(PT(&items[len(items)-1]), columns, false); err != nil {
+				return
+			}
+		}
+		if cur.Backward && more || !cur.Backward && page.Cursor != "" {
+			if result.Prev, err = encodeCursor(PT(&items[0]), columns, true); err != nil {
+				return
+			}
+		}
+	}
+	if page.Total {
+		if result.Total, err = CountContext(ctx, ds, PT(new(T)), q); err != nil {
+			return
+		}
+	}
+	result.Items = items
+	return
+}
+
+// SQL condition selecting rows after cursor values in keyset order
+func keysetCondition(columns []string, desc []bool, values []interface{}) (string, []interface{}) {
+	var args []interface{}
+	or := make([]string, len(columns))
+	for i := range columns {
+		and := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			and = append(and, quoteIdent(columns[j])+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if desc[i] {
+			op = " < ?"
+		}
+		and = append(and, quoteIdent(columns[i])+op)
+		args = append(args, values[i])
+		or[i] = "(" + strings.Join(and, " AND ") + ")"
+	}
+	return strings.Join(or, " OR "), args
+}
+
+func encodeCursor(m Cruder, columns []string, backward bool) (string, error) {
+	cur := cursor{Backward: backward, Values: make([]interface{}, len(columns))}
+	for key, column := range columns {
+		link := columnLink(m, column)
+		if link == nil {
+			return "", errors.New("unknown keyset column " + column)
+		}
+		cur.Values[key] = reflect.ValueOf(link).Elem().Interface()
+	}
+	data, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(token string) (cur cursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		err = ErrInvalidCursor
+		return
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if decoder.Decode(&cur) != nil {
+		err = ErrInvalidCursor
+		return
+	}
+	for key, value := range cur.Values {
+		if number, ok := value.(json.Number); ok {
+			if i, errInt := number.Int64(); errInt == nil {
+				cur.Values[key] = i
+			} else if f, errFloat := number.Float64(); errFloat == nil {
+				cur.Values[key] = f
+			}
+		}
+	}
+	return
+}
+
+// Attribute link of model column
+func columnLink(m Cruder, name string) interface{} {
+	names, links := m.PrimaryKey()
+	cnames, clinks := m.Columns()
+	names = append(names, cnames...)
+	links = append(links, clinks...)
+	for key, value := range names {
+		if value == name {
+			return links[key]
+		}
+	}
+	return nil
+}
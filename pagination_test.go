@@ -0,0 +1,178 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPaginateLocksRows(t *testing.T) {
+	cases := []struct {
+		page Page
+		want string
+	}{
+		{Page{Limit: 2}, `SELECT "id", "name", "order" FROM "public"."probe" WHERE (name = $1) LIMIT 2 FOR UPDATE SKIP LOCKED`},
+		{Page{Limit: 2, Keys: []string{"id"}}, `SELECT "id", "name", "order" FROM "public"."probe" WHERE (name = $1) ORDER BY "id" LIMIT 3 FOR UPDATE SKIP LOCKED`},
+	}
+	for _, c := range cases {
+		db := newTestDB(t)
+		q := NewQuery().Where("name = ?", "a").Lock(LockOptions{Wait: SkipLocked})
+		if _, err := Paginate[probe](db, q, c.page); err != nil {
+			t.Fatal(err)
+		}
+		if queries := db.executed(); len(queries) != 1 || queries[0] != c.want {
+			t.Errorf("executed %q, want %s", queries, c.want)
+		}
+	}
+}
+
+func TestPaginateRejectsLockedTotal(t *testing.T) {
+	db := newTestDB(t)
+	_, err := Paginate[probe](db, NewQuery().ForUpdate(), Page{Total: true})
+	if err != ErrLockedTotal {
+		t.Errorf("Paginate = %v, want ErrLockedTotal", err)
+	}
+	if queries := db.executed(); len(queries) != 0 {
+		t.Errorf("executed %q", queries)
+	}
+	db.push(probeRow(1), testResult{columns: []string{"count"}, rows: [][]driver.Value{{int64(1)}}})
+	if _, err = Paginate[probe](db, NewQuery().ForUpdate(), Page{Total: true, Keys: []string{"id"}}); err != nil {
+		t.Fatal(err)
+	}
+	if queries := db.executed(); len(queries) != 2 || strings.Contains(queries[1], "FOR UPDATE") {
+		t.Errorf("executed %q", queries)
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	cases := []struct {
+		columns []string
+		desc    []bool
+		values  []interface{}
+		want    string
+		args    []interface{}
+	}{
+		{[]string{"id"}, []bool{false}, []interface{}{5}, `("id" > ?)`, []interface{}{5}},
+		{[]string{"created_at", "id"}, []bool{true, true}, []interface{}{"t", 5},
+			`("created_at" < ?) OR ("created_at" = ? AND "id" < ?)`, []interface{}{"t", "t", 5}},
+		{[]string{"name", "id"}, []bool{false, true}, []interface{}{"a", 5},
+			`("name" > ?) OR ("name" = ? AND "id" < ?)`, []interface{}{"a", "a", 5}},
+	}
+	for _, c := range cases {
+		got, args := keysetCondition(c.columns, c.desc, c.values)
+		if got != c.want || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("keysetCondition(%v) = %s %v, want %s %v", c.columns, got, args, c.want, c.args)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	token, err := encodeCursor(&probe{Id: 7, Name: "a"}, []string{"name", "id"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := decodeCursor(token)
+	if err != nil || !cur.Backward || !reflect.DeepEqual(cur.Values, []interface{}{"a", int64(7)}) {
+		t.Errorf("decodeCursor = %+v, %v", cur, err)
+	}
+	if _, err = encodeCursor(&probe{}, []string{"missing"}, false); err == nil {
+		t.Error("unknown keyset column is encoded")
+	}
+	for _, token := range []string{"%%", "bm90IGpzb24"} {
+		if _, err = decodeCursor(token); err != ErrInvalidCursor {
+			t.Errorf("decodeCursor(%s) = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
+
+func TestKeysetPageQueries(t *testing.T) {
+	db := newTestDB(t)
+	rows := [][]driver.Value{{int64(1), "a", int64(1)}, {int64(2), "b", int64(1)}, {int64(3), "c", int64(1)}}
+	db.push(testResult{columns: []string{"id", "name", "order"}, rows: rows})
+	page, err := Paginate[probe](db, nil, Page{Limit: 2, Keys: []string{"-id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Next == "" || page.Prev != "" {
+		t.Fatalf("page %+v", page)
+	}
+	db.push(testResult{columns: []string{"id", "name", "order"}, rows: rows[2:]})
+	if _, err = Paginate[probe](db, nil, Page{Limit: 2, Keys: []string{"-id"}, Cursor: page.Next}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`SELECT "id", "name", "order" FROM "public"."probe" ORDER BY "id" DESC LIMIT 3`,
+		`SELECT "id", "name", "order" FROM "public"."probe" WHERE (("id" < $1)) ORDER BY "id" DESC LIMIT 3`,
+	}
+	if queries := db.executed(); !reflect.DeepEqual(queries, want) {
+		t.Errorf("executed %q, want %q", queries, want)
+	}
+	if args := db.args[1]; len(args) != 1 || args[0] != int64(2) {
+		t.Errorf("arguments %v", args)
+	}
+}
+
+func TestOffsetPageTotal(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{
+		columns: []string{"id", "name", "order", "count"},
+		rows:    [][]driver.Value{{int64(5), "a", int64(1), int64(10)}, {int64(6), "a", int64(1), int64(10)}},
+	})
+	q := NewQuery().Where("name = ?", "a")
+	page, err := Paginate[probe](db, q, Page{Limit: 2, Offset: 4, Total: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[1].Id != 6 || page.Total != 10 || page.Next != "" {
+		t.Errorf("page %+v", page)
+	}
+	// page past the end has no rows to read total from
+	db.push(testResult{columns: []string{"id", "name", "order", "count"}}, testResult{columns: []string{"count"}, rows: [][]driver.Value{{int64(10)}}})
+	if page, err = Paginate[probe](db, q, Page{Limit: 2, Offset: 20, Total: true}); err != nil || len(page.Items) != 0 || page.Total != 10 {
+		t.Errorf("page %+v, %v", page, err)
+	}
+	want := []string{
+		`SELECT "id", "name", "order", COUNT(*) OVER() FROM "public"."probe" WHERE (name = $1) LIMIT 2 OFFSET 4`,
+		`SELECT "id", "name", "order", COUNT(*) OVER() FROM "public"."probe" WHERE (name = $1) LIMIT 2 OFFSET 20`,
+		`SELECT COUNT(*) FROM "public"."probe" WHERE (name = $1)`,
+	}
+	if queries := db.executed(); !reflect.DeepEqual(queries, want) {
+		t.Errorf("executed %q, want %q", queries, want)
+	}
+}
+
+func TestPaginateDefaultLimit(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := Paginate[probe](db, nil, Page{}); err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT "id", "name", "order" FROM "public"."probe" LIMIT 20`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+}
+
+func TestKeysetPageBackward(t *testing.T) {
+	db := newTestDB(t)
+	cursor, err := encodeCursor(&probe{Id: 3}, []string{"id"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]driver.Value{{int64(4), "a", int64(1)}, {int64(5), "a", int64(1)}, {int64(6), "a", int64(1)}}
+	db.push(testResult{columns: []string{"id", "name", "order"}, rows: rows})
+	page, err := Paginate[probe](db, nil, Page{Limit: 2, Keys: []string{"-id"}, Cursor: cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Id != 5 || page.Items[1].Id != 4 || page.Next == "" || page.Prev == "" {
+		t.Fatalf("page %+v", page)
+	}
+	want := `SELECT "id", "name", "order" FROM "public"."probe" WHERE (("id" > $1)) ORDER BY "id" LIMIT 3`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+	if _, err = Paginate[probe](db, nil, Page{Keys: []string{"id", "name"}, Cursor: cursor}); err != ErrInvalidCursor {
+		t.Errorf("cursor of other keys: %v, want ErrInvalidCursor", err)
+	}
+}
@@ -16,18 +16,27 @@ var dbo DSLer
 
 // Column information
 type Column struct {
-	Name         string  // DB column name
-	ModelName    string  // Model name
-	Default      *string // DB default value
-	IsNullable   bool    // DB is nullable
-	DataType     string  // DB column type
-	ModelType    string  // Model type
-	Schema       string  // DB Schema
-	Table        string  // DB table
-	Sequence     *string // DB sequence
-	IsPrimaryKey bool    // DB is primary key
-	Json         string  // Model Json name
-	Import       string  // Model Import custom lib
+	Name         string     // DB column name
+	ModelName    string     // Model name
+	Default      *string    // DB default value
+	IsNullable   bool       // DB is nullable
+	DataType     string     // DB column type
+	ModelType    string     // Model type
+	Schema       string     // DB Schema
+	Table        string     // DB table
+	Sequence     *string    // DB sequence
+	IsPrimaryKey bool       // DB is primary key
+	Json         string     // Model Json name
+	Import       string     // Model Import custom lib
+	Reference    *Reference // DB foreign key
+}
+
+// Foreign key information
+type Reference struct {
+	Name   string // Model relation name
+	Model  string // Referenced model name
+	Table  string // Referenced DB table
+	Column string // Referenced DB column
 }
 
 // Array of columns
@@ -172,10 +181,95 @@ ORDER BY a.attnum;
 		}
 	}
 
+	references, err := getTableReferences(schema, table)
+	if err != nil {
+		return nil, err
+	}
+	for key, column := range columns {
+		if reference, ok := references[column.Name]; ok {
+			columns[key].Reference = &reference
+		}
+	}
+
 	return &columns, nil
 }
 
-// Start script
+// Get single column foreign keys of table by column name
+func getTableReferences(schema string, table string) (map[string]Reference, error) {
+	query := fmt.Sprintf(`
+SELECT a.attname  AS column_name,
+       rt.relname AS referenced_table,
+       ra.attname AS referenced_column
+FROM pg_constraint c
+       JOIN pg_class t ON t.oid = c.conrelid
+       JOIN pg_namespace s ON s.oid = t.relnamespace
+       JOIN pg_class rt ON rt.oid = c.confrelid AND rt.relnamespace = t.relnamespace
+       JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = c.conkey[1]
+       JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = c.confkey[1]
+WHERE c.contype = 'f'
+  AND array_length(c.conkey, 1) = 1
+  AND s.nspname = '%s'
+  AND t.relname = '%s'
+ORDER BY a.attnum;
+`, schema, table)
+
+	rows, err := dbo.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	references := make(map[string]Reference)
+	for rows.Next() {
+		var column string
+		var reference Reference
+		if err := rows.Scan(&column, &reference.Table, &reference.Column); err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(column, "_id")
+		if name == column {
+			name += "_ref"
+		}
+		if reference.Name, err = toCamelCase(name, true); err != nil {
+			return nil, err
+		}
+		if reference.Model, err = toCamelCase(reference.Table, true); err != nil {
+			return nil, err
+		}
+		references[column] = reference
+	}
+
+	return references, rows.Err()
+}
+
+// Drop references which would not compile: referenced model is neither the table itself
+// nor generated into path, or relation field is named as another field of model
+func resolveReferences(columns Columns, table string, path string) {
+	names := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		names[column.ModelName] = true
+	}
+	for key, column := range columns {
+		reference := column.Reference
+		if reference == nil {
+			continue
+		}
+		if names[reference.Name] {
+			columns[key].Reference = nil
+			continue
+		}
+		if reference.Table != table {
+			if _, err := os.Stat(fmt.Sprintf("%s/%s.go", path, reference.Table)); err != nil {
+				columns[key].Reference = nil
+				continue
+			}
+		}
+		names[reference.Name] = true
+	}
+}
+
+// Start script. Foreign keys get relation fields only when referenced table is already generated into path,
+// so generate referenced tables first
 func MakeModel(db DSLer, path string, schema string, table string) error {
 	if table == "" {
 		return errors.New("table name is empty")
@@ -205,11 +299,9 @@ func CreateModelFile(schema string, table string, path string) (*os.File, string
 // Get model file header
 func getModelHeader(imports []string) (bytes.Buffer, error) {
 	baseImports := []string{
-		`"strings"`,
+		`"context"`,
 		`"database/sql"`,
 		`"errors"`,
-		`"fmt"`,
-		`"github.com/dimonrus/godb"`,
 		`"github.com/cadyrov/gocrud"`,
 		`"github.com/cadyrov/govalidation"`,
 	}
@@ -234,7 +326,10 @@ import ({{ range $key, $import := .Imports }}{{ $import }}
 // Get model struct
 func getModelStruct(model string, table string, columns Columns) (bytes.Buffer, error) {
 	t := `type {{ .Model }} struct { {{ range $key, $column := .Columns }}
-	{{ $column.ModelName }} {{ $column.ModelType }} {{ $column.Json }}{{ end }}
+	{{ $column.ModelName }} {{ $column.ModelType }} {{ $column.Json }}{{ end }}{{ range $key, $column := .Columns }}{{ if $column.Reference }}
+	{{ $column.Reference.Name }} *{{ $column.Reference.Model }} ` + "`json:\"-\"`" + `{{ end }}{{ end }}{{ if not (version .Columns) }}
+	Xmin int64 ` + "`json:\"-\"`" + `{{ end }}
+	crud.Tracker ` + "`json:\"-\"`" + `
 }
 `
 	return ParseCrudMethodTemplate(t, model, table, columns)
@@ -302,6 +397,22 @@ func ParseCrudMethodTemplate(t string, model string, table string, columns Colum
 			return existsInArrayString(column.Name, []string{"updated_at", "created_at", "deleted_at"}) ||
 				(column.IsPrimaryKey && column.Sequence != nil)
 		},
+		"column": func(columns Columns, name string) *Column {
+			for key := range columns {
+				if columns[key].Name == name {
+					return &columns[key]
+				}
+			}
+			return nil
+		},
+		"version": func(columns Columns) *Column {
+			for key := range columns {
+				if existsInArrayString(columns[key].Name, []string{"version", "lock_version"}) {
+					return &columns[key]
+				}
+			}
+			return nil
+		},
 	}
 
 	tml := template.Must(template.New("").Funcs(funcMap).Parse(t))
@@ -326,6 +437,24 @@ func (m *{{ .Model }}) Load(d crud.DSLer) (ok bool, err error) {
 	ok, err = crud.Load(d, m)
 	return
 }
+
+// Load {{ .Model }} with context
+func (m *{{ .Model }}) LoadContext(ctx context.Context, d crud.DSLerContext) (ok bool, err error) {
+	ok, err = crud.LoadContext(ctx, d, m)
+	return
+}
+
+// Load {{ .Model }} and lock its row until the end of transaction
+func (m *{{ .Model }}) LoadForUpdate(tx crud.DSLer, opts crud.LockOptions) (ok bool, err error) {
+	ok, err = crud.LoadForUpdate(tx, m, opts)
+	return
+}
+
+// Load and lock {{ .Model }} with context
+func (m *{{ .Model }}) LoadForUpdateContext(ctx context.Context, tx crud.DSLerContext, opts crud.LockOptions) (ok bool, err error) {
+	ok, err = crud.LoadForUpdateContext(ctx, tx, m, opts)
+	return
+}
 `
 	return ParseCrudMethodTemplate(t, model, table, columns)
 }
@@ -337,10 +466,81 @@ func getModelDeleter(model string, table string, columns Columns) (bytes.Buffer,
 func (m *{{ .Model }}) Delete(d crud.DSLer) error {
 	return crud.Delete(d, m)
 }
+
+// Delete {{ .Model }} with context
+func (m *{{ .Model }}) DeleteContext(ctx context.Context, d crud.DSLerContext) error {
+	return crud.DeleteContext(ctx, d, m)
+}
 `
 	return ParseCrudMethodTemplate(t, model, table, columns)
 }
 
+// Get model soft deleter
+func getModelSoftDeleter(model string, table string, columns Columns) (bytes.Buffer, error) {
+	t := `{{ range $key, $column := .Columns }}{{ if eq $column.Name "deleted_at" }}
+// Soft delete column of {{ $.Model }}
+func (m *{{ $.Model }}) SoftDeleteColumn() (name string, attributeLink interface{}) {
+	return "{{ $column.Name }}", &m.{{ $column.ModelName }}
+}
+
+// Delete {{ $.Model }} permanently
+func (m *{{ $.Model }}) HardDelete(d crud.DSLer) error {
+	return crud.HardDelete(d, m)
+}
+
+// Restore soft deleted {{ $.Model }}
+func (m *{{ $.Model }}) Restore(d crud.DSLer) error {
+	return crud.Restore(d, m)
+}
+{{ end }}{{ end }}`
+	return ParseCrudMethodTemplate(t, model, table, columns)
+}
+
+// Get model versioned, tables without version or lock_version column are versioned by xmin
+func getModelVersioned(model string, table string, columns Columns) (bytes.Buffer, error) {
+	t := `{{ with version .Columns }}
+// Optimistic lock column of {{ $.Model }}
+func (m *{{ $.Model }}) VersionColumn() (name string, attributeLink interface{}) {
+	return "{{ .Name }}", &m.{{ .ModelName }}
+}
+{{ else }}
+// Optimistic lock of {{ $.Model }} by row version of system column xmin
+func (m *{{ $.Model }}) VersionColumn() (name string, attributeLink interface{}) {
+	return "xmin", &m.Xmin
+}
+{{ end }}`
+	return ParseCrudMethodTemplate(t, model, table, columns)
+}
+
+// Get model timestamps
+func getModelTimestamped(model string, table string, columns Columns) (bytes.Buffer, error) {
+	t := `{{ $created := column .Columns "created_at" }}{{ $updated := column .Columns "updated_at" }}{{ if or $created $updated }}
+// Creation time column of {{ .Model }}
+func (m *{{ .Model }}) CreatedAtColumn() (name string, attributeLink interface{}) {
+	{{ with $created }}return "{{ .Name }}", &m.{{ .ModelName }}{{ else }}return{{ end }}
+}
+
+// Modification time column of {{ .Model }}
+func (m *{{ .Model }}) UpdatedAtColumn() (name string, attributeLink interface{}) {
+	{{ with $updated }}return "{{ .Name }}", &m.{{ .ModelName }}{{ else }}return{{ end }}
+}
+{{ end }}`
+	return ParseCrudMethodTemplate(t, model, table, columns)
+}
+
+// Get model relations
+func getModelRelations(model string, table string, columns Columns) (bytes.Buffer, error) {
+	t := `{{ $relations := false }}{{ range $key, $column := .Columns }}{{ if $column.Reference }}{{ $relations = true }}{{ end }}{{ end }}{{ if $relations }}
+// Relations of {{ .Model }}, loaded with crud.Preload
+func (m *{{ .Model }}) Relations() map[string]crud.Relation {
+	return map[string]crud.Relation{ {{ range $key, $column := .Columns }}{{ if $column.Reference }}
+		"{{ $column.Reference.Name }}": {Kind: crud.KindBelongsTo, ForeignKey: "{{ $column.Name }}", References: "{{ $column.Reference.Column }}"},{{ end }}{{ end }}
+	}
+}
+{{ end }}`
+	return ParseCrudMethodTemplate(t, model, table, columns)
+}
+
 // Get model saver
 func getModelSaver(model string, table string, columns Columns) (bytes.Buffer, error) {
 
@@ -349,44 +549,42 @@ func getModelSaver(model string, table string, columns Columns) (bytes.Buffer, e
 func (m *{{ .Model }}) Save(d crud.DSLer) error {
 	return crud.Save(d, m)
 }
-`
-	return ParseCrudMethodTemplate(t, model, table, columns)
+
+// Save {{ .Model }} with context
+func (m *{{ .Model }}) SaveContext(ctx context.Context, d crud.DSLerContext) error {
+	return crud.SaveContext(ctx, d, m)
 }
 
-// Get model searcher
-func getModelSearcher(model string, table string, columns Columns) (bytes.Buffer, error) {
-	t := `// Search by filer
-func (m *{{ .Model }}) Search (q crud.DSLer, filter godb.SqlFilter) ([]{{ .Model }}, {{ $index := 0 }}{{ range $key, $column := .Columns }}{{ if $column.IsPrimaryKey }}{{ if $index }}, {{ end }}{{ $index = inc $index }}[]{{ $column.ModelType }}{{ end }}{{ end }}, error) {
-	nm,_ := m.Columns()
-	names, _ := m.PrimaryKey()
-	names = append(names, nm...)
-	query := fmt.Sprintf("SELECT " + strings.Join(names, ",") + " FROM {{ .Table }} %s", filter.String())
-	rows, err := q.Query(query, filter.GetArguments()...)
-	{{ range $key, $column := .Columns }}{{ if $column.IsPrimaryKey }}
-	entity{{ $column.ModelName }}s := make([]{{ $column.ModelType }}, 0){{ end }}{{ end }}
-	if err != nil {
-		return nil, {{ $index := 0 }}{{ range $key, $column := .Columns }}{{ if $column.IsPrimaryKey }}{{ if $index }}, {{ end }}{{ $index = inc $index }}entity{{ $column.ModelName }}s{{ end }}{{ end }}, err
-	}
-	defer rows.Close()
-	var result = []{{ .Model }}{}
-	for rows.Next() {
-		row := &{{ .Model }}{}
-		_, sc := row.Columns()
-		_, scans := row.PrimaryKey()
+// Insert {{ .Model }}
+func (m *{{ .Model }}) Insert(d crud.DSLer) (bool, error) {
+	return crud.Insert(d, m)
+}
 
-		scans = append(scans, sc...)
-		err = rows.Scan(scans...)
-		if err != nil {
-			return result, {{ $index := 0 }}{{ range $key, $column := .Columns }}{{ if $column.IsPrimaryKey }}{{ if $index }}, {{ end }}{{ $index = inc $index }}entity{{ $column.ModelName }}s{{ end }}{{ end }}, err
-		}
-		{{ range $key, $column := .Columns }}{{ if $column.IsPrimaryKey }}
-		entity{{ $column.ModelName }}s = append(entity{{ $column.ModelName }}s, row.{{ $column.ModelName}}){{ end }}{{ end }}
-		result = append(result, *row)
-	}
-	return result, {{ $index := 0 }}{{ range $key, $column := .Columns }}{{ if $column.IsPrimaryKey }}{{ if $index }}, {{ end }}{{ $index = inc $index }}entity{{ $column.ModelName }}s{{ end }}{{ end }}, nil
+// Insert {{ .Model }} with context
+func (m *{{ .Model }}) InsertContext(ctx context.Context, d crud.DSLerContext) (bool, error) {
+	return crud.InsertContext(ctx, d, m)
+}
+
+// Update {{ .Model }}
+func (m *{{ .Model }}) Update(d crud.DSLer) (bool, error) {
+	return crud.Update(d, m)
+}
+
+// Update {{ .Model }} with context
+func (m *{{ .Model }}) UpdateContext(ctx context.Context, d crud.DSLerContext) (bool, error) {
+	return crud.UpdateContext(ctx, d, m)
+}
+
+// Upsert {{ .Model }}
+func (m *{{ .Model }}) Upsert(d crud.DSLer) (bool, error) {
+	return crud.Upsert(d, m)
 }
-`
 
+// Upsert {{ .Model }} with context
+func (m *{{ .Model }}) UpsertContext(ctx context.Context, d crud.DSLerContext) (bool, error) {
+	return crud.UpsertContext(ctx, d, m)
+}
+`
 	return ParseCrudMethodTemplate(t, model, table, columns)
 }
 
@@ -410,6 +608,8 @@ func CreateModel(schema string, table string, path string) error {
 		return errors.New(fmt.Sprintf("table (%s) is not exists", table))
 	}
 
+	resolveReferences(*columns, table, path)
+
 	// Name of the model
 	modelName, err := toCamelCase(table, true)
 	if err != nil {
@@ -463,12 +663,27 @@ func CreateModel(schema string, table string, path string) error {
 		return err
 	}
 
-	saver, err := getModelSaver(modelName, tableName, *columns)
+	softDeleter, err := getModelSoftDeleter(modelName, tableName, *columns)
+	if err != nil {
+		return err
+	}
+
+	versioned, err := getModelVersioned(modelName, tableName, *columns)
 	if err != nil {
 		return err
 	}
 
-	searcher, err := getModelSearcher(modelName, tableName, *columns)
+	timestamped, err := getModelTimestamped(modelName, tableName, *columns)
+	if err != nil {
+		return err
+	}
+
+	relations, err := getModelRelations(modelName, tableName, *columns)
+	if err != nil {
+		return err
+	}
+
+	saver, err := getModelSaver(modelName, tableName, *columns)
 	if err != nil {
 		return err
 	}
@@ -508,12 +723,27 @@ func CreateModel(schema string, table string, path string) error {
 		return err
 	}
 
-	_, err = file.Write(saver.Bytes())
+	_, err = file.Write(softDeleter.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(versioned.Bytes())
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(searcher.Bytes())
+	_, err = file.Write(timestamped.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(relations.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(saver.Bytes())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,55 @@
+package crud
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveReferences(t *testing.T) {
+	path := t.TempDir()
+	if err := os.WriteFile(filepath.Join(path, "user.go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	columns := Columns{
+		{Name: "author", ModelName: "Author"},
+		{Name: "author_id", ModelName: "AuthorId", Reference: &Reference{Name: "Author", Model: "User", Table: "user"}},
+		{Name: "owner_id", ModelName: "OwnerId", Reference: &Reference{Name: "Owner", Model: "User", Table: "user"}},
+		{Name: "group_id", ModelName: "GroupId", Reference: &Reference{Name: "Group", Model: "Group", Table: "group"}},
+		{Name: "parent_id", ModelName: "ParentId", Reference: &Reference{Name: "Parent", Model: "Post", Table: "post"}},
+	}
+	resolveReferences(columns, "post", path)
+	want := map[string]bool{"author_id": false, "owner_id": true, "group_id": false, "parent_id": true}
+	for _, column := range columns[1:] {
+		if kept := column.Reference != nil; kept != want[column.Name] {
+			t.Errorf("reference of %s kept: %v, want %v", column.Name, kept, want[column.Name])
+		}
+	}
+}
+
+func TestModelVersioned(t *testing.T) {
+	cases := []struct {
+		columns Columns
+		want    string
+	}{
+		{Columns{{Name: "id", ModelName: "Id"}, {Name: "lock_version", ModelName: "LockVersion"}}, `return "lock_version", &m.LockVersion`},
+		{Columns{{Name: "id", ModelName: "Id"}, {Name: "title", ModelName: "Title"}}, `return "xmin", &m.Xmin`},
+	}
+	for _, c := range cases {
+		versioned, err := getModelVersioned("Post", "post", c.columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(versioned.String(), c.want) {
+			t.Errorf("versioned %s, want %s", versioned.String(), c.want)
+		}
+		model, err := getModelStruct("Post", "post", c.columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if xmin := strings.Contains(model.String(), "Xmin int64"); xmin != strings.Contains(c.want, "xmin") {
+			t.Errorf("struct %s", model.String())
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package crud
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Query condition
+type condition struct {
+	operator string
+	sql      string
+	args     []interface{}
+}
+
+// SQL query builder producing numbered placeholders. Implements Filter
+type Query struct {
+	model      Cruder
+	conditions []condition
+	scopes     []condition
+	orders     []string
+	limit      int
+	offset     int
+	lock       *LockOptions
+	err        error // invalid column of condition
+}
+
+// New query builder
+func NewQuery() *Query {
+	return &Query{}
+}
+
+// New query builder selecting rows of model table
+func Select(m Cruder) *Query {
+	return &Query{model: m}
+}
+
+// Add condition joined with AND. Each ? is replaced by numbered placeholder, ?? stays as ?
+func (q *Query) Where(sql string, args ...interface{}) *Query {
+	q.conditions = append(q.conditions, condition{operator: "AND", sql: sql, args: args})
+	return q
+}
+
+// Add condition joined with AND
+func (q *Query) And(sql string, args ...interface{}) *Query {
+	return q.Where(sql, args...)
+}
+
+// Add condition joined with OR
+func (q *Query) Or(sql string, args ...interface{}) *Query {
+	q.conditions = append(q.conditions, condition{operator: "OR", sql: sql, args: args})
+	return q
+}
+
+// Add column IN (values) condition, empty values match nothing.
+// Column names of condition helpers are quoted, use Where for expressions
+func (q *Query) In(column string, values ...interface{}) *Query {
+	column = q.column(column)
+	if len(values) == 0 {
+		return q.Where("FALSE")
+	}
+	return q.Where(column+" IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")+")", values...)
+}
+
+// Add column BETWEEN from AND to condition
+func (q *Query) Between(column string, from interface{}, to interface{}) *Query {
+	return q.Where(q.column(column)+" BETWEEN ? AND ?", from, to)
+}
+
+// Add column LIKE pattern condition
+func (q *Query) Like(column string, pattern string) *Query {
+	return q.Where(q.column(column)+" LIKE ?", pattern)
+}
+
+// Add column IS NULL condition
+func (q *Query) IsNull(column string) *Query {
+	return q.Where(q.column(column) + " IS NULL")
+}
+
+// Quoted column name, invalid name fails the query with ErrInvalidIdentifier when it is run
+func (q *Query) column(name string) string {
+	if err := checkIdentifier(name); err != nil && q.err == nil {
+		q.err = err
+	}
+	return quoteIdent(name)
+}
+
+// Add order expressions, e.g. "name", "id DESC"
+func (q *Query) OrderBy(expressions ...string) *Query {
+	q.orders = append(q.orders, expressions...)
+	return q
+}
+
+// Limit rows count, zero means no limit
+func (q *Query) Limit(limit int) *Query {
+	q.limit = limit
+	return q
+}
+
+// Skip rows
+func (q *Query) Offset(offset int) *Query {
+	q.offset = offset
+	return q
+}
+
+// Filter part of query: WHERE, ORDER BY, LIMIT and OFFSET
+func (q *Query) String() string {
+	sql, _ := q.render("")
+	return sql
+}
+
+// Arguments in order of placeholders
+func (q *Query) GetArguments() []interface{} {
+	_, args := q.render("")
+	return args
+}
+
+// Full SELECT statement with arguments for model of query. Query without model fails with ErrNoModel
+func (q *Query) SQL() (query string, args []interface{}, err error) {
+	if q.model == nil {
+		err = ErrNoModel
+		return
+	}
+	if err = checkSearch(q.model, q); err != nil {
+		return
+	}
+	return SearchQuery(q.model, q) + lockClause(DefaultDialect, q), Arguments(q), nil
+}
+
+// Copy of query which can be changed independently
+func (q *Query) clone() *Query {
+	cp := *q
+	cp.conditions = append([]condition{}, q.conditions...)
+	cp.scopes = append([]condition{}, q.scopes...)
+	cp.orders = append([]string{}, q.orders...)
+	return &cp
+}
+
+// Add condition joined with AND to the whole group of Where, And and Or conditions
+func (q *Query) scope(sql string, args ...interface{}) *Query {
+	q.scopes = append(q.scopes, condition{operator: "AND", sql: sql, args: args})
+	return q
+}
+
+// Render filter part with extra condition which is always applied
+func (q *Query) render(extra string) (string, []interface{}) {
+	var b strings.Builder
+	var args []interface{}
+	parts := make([]string, 0, len(q.scopes)+2)
+	if extra != "" {
+		parts = append(parts, extra)
+	}
+	for _, c := range q.scopes {
+		parts = append(parts, "("+numberPlaceholders(c.sql, len(args))+")")
+		args = append(args, c.args...)
+	}
+	where := make([]string, 0, len(q.conditions))
+	for _, c := range q.conditions {
+		sql := numberPlaceholders(c.sql, len(args))
+		args = append(args, c.args...)
+		if len(where) == 0 {
+			where = append(where, "("+sql+")")
+		} else {
+			where = append(where, c.operator+" ("+sql+")")
+		}
+	}
+	if len(where) > 0 && len(parts) > 0 {
+		parts = append(parts, "("+strings.Join(where, " ")+")")
+	} else if len(where) > 0 {
+		parts = append(parts, strings.Join(where, " "))
+	}
+	if len(parts) > 0 {
+		b.WriteString("WHERE " + strings.Join(parts, " AND "))
+	}
+	if len(q.orders) > 0 {
+		b.WriteString(" ORDER BY " + strings.Join(q.orders, ", "))
+	}
+	if q.limit > 0 {
+		b.WriteString(" LIMIT " + strconv.Itoa(q.limit))
+	}
+	if q.offset > 0 {
+		b.WriteString(" OFFSET " + strconv.Itoa(q.offset))
+	}
+	return strings.TrimSpace(b.String()), args
+}
+
+// Replace ? with $n starting after given count
+func numberPlaceholders(sql string, count int) string {
+	var b strings.Builder
+	for i := 0; i < len(sql); i++ {
+		if sql[i] != '?' {
+			b.WriteByte(sql[i])
+			continue
+		}
+		if i+1 < len(sql) && sql[i+1] == '?' {
+			b.WriteByte('?')
+			i++
+			continue
+		}
+		count++
+		b.WriteString("$" + strconv.Itoa(count))
+	}
+	return b.String()
+}
+
+// Arguments of filter, nil filter has none
+func Arguments(filter Filter) []interface{} {
+	filter = unscope(filter)
+	if filter == nil {
+		return nil
+	}
+	return filter.GetArguments()
+}
@@ -0,0 +1,91 @@
+package crud
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQuerySQL(t *testing.T) {
+	query, args, err := Select(&probe{}).Where("name = ?", "a").ForUpdate().SQL()
+	want := `SELECT "id", "name", "order" FROM "public"."probe" WHERE (name = $1) FOR UPDATE`
+	if err != nil || oneLine(query) != want || len(args) != 1 || args[0] != "a" {
+		t.Errorf("SQL() = %s, %v, %v, want %s", query, args, err, want)
+	}
+	if _, _, err = NewQuery().Where("id = ?", 1).SQL(); err != ErrNoModel {
+		t.Errorf("query without model: %v, want ErrNoModel", err)
+	}
+}
+
+func TestQueryRender(t *testing.T) {
+	cases := []struct {
+		query *Query
+		extra string
+		want  string
+		args  []interface{}
+	}{
+		{NewQuery(), "", "", nil},
+		{NewQuery(), `"deleted_at" IS NULL`, `WHERE "deleted_at" IS NULL`, nil},
+		{NewQuery().Where("a = ?", 1).Or("b = ? OR c ?? d", 2), "",
+			"WHERE (a = $1) OR (b = $2 OR c ? d)", []interface{}{1, 2}},
+		{NewQuery().Where("a = ?", 1).Or("b = ?", 2), `"deleted_at" IS NULL`,
+			`WHERE "deleted_at" IS NULL AND ((a = $1) OR (b = $2))`, []interface{}{1, 2}},
+		{NewQuery().Where("a = ?", 1).scope("b > ?", 2), "",
+			"WHERE (b > $1) AND ((a = $2))", []interface{}{2, 1}},
+		{NewQuery().In("id", 1, 2).Between("n", 3, 4).Like("s", "x%").IsNull("d"), "",
+			`WHERE ("id" IN ($1, $2)) AND ("n" BETWEEN $3 AND $4) AND ("s" LIKE $5) AND ("d" IS NULL)`, []interface{}{1, 2, 3, 4, "x%"}},
+		{NewQuery().In("id"), "", "WHERE (FALSE)", nil},
+		{NewQuery().In("order", 1).IsNull("p.deleted_at"), "", `WHERE ("order" IN ($1)) AND ("p"."deleted_at" IS NULL)`, []interface{}{1}},
+		{NewQuery().OrderBy("a", "b DESC").Limit(10).Offset(20), "", "ORDER BY a, b DESC LIMIT 10 OFFSET 20", nil},
+	}
+	for _, c := range cases {
+		got, args := c.query.render(c.extra)
+		if got != c.want || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("render = %s %v, want %s %v", got, args, c.want, c.args)
+		}
+	}
+}
+
+func TestQueryClone(t *testing.T) {
+	q := NewQuery().Where("a = ?", 1).OrderBy("a")
+	cp := q.clone().Where("b = ?", 2).OrderBy("b")
+	if got := q.String(); got != "WHERE (a = $1) ORDER BY a" {
+		t.Errorf("original query changed: %s", got)
+	}
+	if got := cp.String(); got != "WHERE (a = $1) AND (b = $2) ORDER BY a, b" {
+		t.Errorf("clone = %s", got)
+	}
+}
+
+func TestArguments(t *testing.T) {
+	q := NewQuery().Where("a = ?", 1)
+	if args := Arguments(WithDeleted(q)); !reflect.DeepEqual(args, []interface{}{1}) {
+		t.Errorf("arguments of unscoped query %v", args)
+	}
+	if args := Arguments(nil); args != nil {
+		t.Errorf("arguments of nil filter %v", args)
+	}
+}
+
+func TestQueryRejectsInvalidColumn(t *testing.T) {
+	q := NewQuery().Like(`name" OR 1=1 --`, "a")
+	if _, _, err := Select(&probe{}).Like(`name" OR 1=1 --`, "a").SQL(); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("SQL() = %v, want ErrInvalidIdentifier", err)
+	}
+	db := newTestDB(t)
+	if _, err := Find[probe](db, q); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("Find = %v, want ErrInvalidIdentifier", err)
+	}
+	if _, err := Count(db, &probe{}, WithDeleted(q)); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("Count = %v, want ErrInvalidIdentifier", err)
+	}
+	if _, err := DeleteWhere(db, &probe{}, q); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("DeleteWhere = %v, want ErrInvalidIdentifier", err)
+	}
+	if _, err := Paginate[probe](db, q, Page{}); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("Paginate = %v, want ErrInvalidIdentifier", err)
+	}
+	if queries := db.executed(); len(queries) != 0 {
+		t.Errorf("executed %q", queries)
+	}
+}
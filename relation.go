@@ -0,0 +1,284 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Kind of relation between models
+type RelationKind int
+
+const (
+	// Model column ForeignKey references related model
+	KindBelongsTo RelationKind = iota
+	// Related model column ForeignKey references model, at most one related row
+	KindHasOne
+	// Related model column ForeignKey references model
+	KindHasMany
+	// Join table links model and related model
+	KindManyToMany
+)
+
+// Relation of model to related model. Related models are assigned to field of model named as relation:
+// pointer or struct for BelongsTo and HasOne, slice of structs or pointers for HasMany and ManyToMany
+type Relation struct {
+	Kind RelationKind
+	// BelongsTo: column of model; HasOne, HasMany: column of related model;
+	// ManyToMany: column of join table referencing model
+	ForeignKey string
+	// Referenced column: of related model for BelongsTo, of model otherwise. First primary key column if empty
+	References string
+	// ManyToMany join table and its column referencing related model
+	JoinTable      string
+	JoinForeignKey string
+	// ManyToMany column of related model referenced by join table. First primary key column if empty
+	JoinReferences string
+}
+
+// Model with relations by name
+type Relater interface {
+	Relations() map[string]Relation
+}
+
+// Model column foreignKey references related model
+func BelongsTo(foreignKey string) Relation {
+	return Relation{Kind: KindBelongsTo, ForeignKey: foreignKey}
+}
+
+// Related model column foreignKey references model, at most one related row
+func HasOne(foreignKey string) Relation {
+	return Relation{Kind: KindHasOne, ForeignKey: foreignKey}
+}
+
+// Related model column foreignKey references model
+func HasMany(foreignKey string) Relation {
+	return Relation{Kind: KindHasMany, ForeignKey: foreignKey}
+}
+
+// Join table links model by foreignKey column and related model by joinForeignKey column
+func ManyToMany(joinTable string, foreignKey string, joinForeignKey string) Relation {
+	return Relation{Kind: KindManyToMany, JoinTable: joinTable, ForeignKey: foreignKey, JoinForeignKey: joinForeignKey}
+}
+
+// Load related models of all models with one query per relation and assign them to relation fields.
+// Keys above bind parameter limit are loaded by several queries
+func Preload[T any, PT Model[T]](ds DSLer, models []T, relations ...string) error {
+	return PreloadContext[T, PT](context.Background(), WithContext(ds), models, relations...)
+}
+
+// Load related models with context
+func PreloadContext[T any, PT Model[T]](ctx context.Context, ds DSLerContext, models []T, relations ...string) error {
+	owners := make([]Cruder, len(models))
+	for key := range models {
+		owners[key] = PT(&models[key])
+	}
+	return PreloadModels(ctx, ds, owners, relations...)
+}
+
+// Load related models of models of one type with context
+func PreloadModels(ctx context.Context, ds DSLerContext, models []Cruder, relations ...string) (err error) {
+	if len(models) == 0 {
+		return
+	}
+	for _, name := range relations {
+		if err = preload(ctx, ds, models, name); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func preload(ctx context.Context, ds DSLerContext, owners []Cruder, name string) (err error) {
+	relater, ok := owners[0].(Relater)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRelation, name)
+	}
+	rel, ok := relater.Relations()[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRelation, name)
+	}
+	field, ok := reflect.TypeOf(owners[0]).Elem().FieldByName(name)
+	if !ok {
+		return fmt.Errorf("%w: model has no field %s", ErrUnknownRelation, name)
+	}
+	target := relatedType(field.Type)
+	if !reflect.PtrTo(target).Implements(reflect.TypeOf((*Cruder)(nil)).Elem()) {
+		return fmt.Errorf("%w: %s is not a model", ErrUnknownRelation, target)
+	}
+	proto := reflect.New(target).Interface().(Cruder)
+	if err = checkIdentifiers(proto); err != nil {
+		return
+	}
+	ownerKey, relatedKey := rel.ForeignKey, rel.ForeignKey
+	switch rel.Kind {
+	case KindBelongsTo:
+		relatedKey = referencedColumn(proto, rel.References)
+	case KindManyToMany:
+		ownerKey = referencedColumn(owners[0], rel.References)
+	default:
+		ownerKey = referencedColumn(owners[0], rel.References)
+	}
+	keys := make([]interface{}, 0, len(owners))
+	seen := map[string]bool{}
+	for _, owner := range owners {
+		value := argumentValue(columnLink(owner, ownerKey))
+		if value == nil {
+			continue
+		}
+		if key := relationKey(value); !seen[key] {
+			seen[key] = true
+			keys = append(keys, value)
+		}
+	}
+	related := map[string][]reflect.Value{}
+	for start := 0; start < len(keys); start += maxQueryParams {
+		end := start + maxQueryParams
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err = loadRelated(ctx, ds, proto, rel, relatedKey, keys[start:end], related); err != nil {
+			return
+		}
+	}
+	for _, owner := range owners {
+		var values []reflect.Value
+		if value := argumentValue(columnLink(owner, ownerKey)); value != nil {
+			values = related[relationKey(value)]
+		}
+		assignRelated(reflect.ValueOf(owner).Elem().FieldByIndex(field.Index), values)
+	}
+	return
+}
+
+// Model struct type of relation field
+func relatedType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// Referenced column or first primary key column
+func referencedColumn(m Cruder, column string) string {
+	if column != "" {
+		return column
+	}
+	names, _ := m.PrimaryKey()
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// Comparable key of column value, e.g. int64 and *int64 foreign keys match int64 primary key
+func relationKey(value interface{}) string {
+	return fmt.Sprint(value)
+}
+
+// Load related models matching keys into related grouped by key. Keys must fit bind parameter limit
+func loadRelated(ctx context.Context, ds DSLerContext, proto Cruder, rel Relation, relatedKey string, keys []interface{}, related map[string][]reflect.Value) (err error) {
+	for _, name := range []string{relatedKey, rel.JoinTable, rel.JoinForeignKey, rel.JoinReferences} {
+		if name != "" {
+			if err = checkIdentifier(name); err != nil {
+				return
+			}
+		}
+	}
+	var query string
+	if rel.Kind == KindManyToMany {
+		query = manyToManyQuery(proto, rel, len(keys))
+	} else {
+		query = SearchQuery(proto, NewQuery().In(relatedKey, keys...))
+	}
+	rows, err := queryContext(withOperation(ctx, OpLoad, proto), ds, query, keys...)
+	if err != nil {
+		err = dbError(err)
+		return
+	}
+	defer rows.Close()
+	target := reflect.TypeOf(proto).Elem()
+	var loaded []Cruder
+	for rows.Next() {
+		value := reflect.New(target)
+		m := value.Interface().(Cruder)
+		dest := scans(m)
+		var key interface{}
+		if rel.Kind == KindManyToMany {
+			dest = append(dest, &key)
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return
+		}
+		if rel.Kind != KindManyToMany {
+			key = argumentValue(columnLink(m, relatedKey))
+		}
+		if b, ok := key.([]byte); ok {
+			key = string(b)
+		}
+		related[relationKey(key)] = append(related[relationKey(key)], value)
+		loaded = append(loaded, m)
+	}
+	if err = dbError(rows.Err()); err != nil {
+		return
+	}
+	rows.Close()
+	for _, m := range loaded {
+		if err = Loaded(ctx, ds, m); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// SQL of related models joined with key of model from join table
+func manyToManyQuery(proto Cruder, rel Relation, count int) string {
+	alias := quoteIdent(tableAlias(proto))
+	join := quoteIdent(rel.JoinTable)
+	params := make([]string, count)
+	for key := range params {
+		params[key] = "$" + strconv.Itoa(key+1)
+	}
+	all := alias + ".*"
+	if name, _, ok := systemVersion(proto); ok {
+		all += ", " + alias + "." + name
+	}
+	query := "SELECT " + all + ", " + join + "." + quoteIdent(rel.ForeignKey) + " AS crud_owner_key" +
+		" FROM " + quoteIdent(proto.TableName()) + " AS " + alias +
+		" JOIN " + join + " ON " + join + "." + quoteIdent(rel.JoinForeignKey) + " = " + alias + "." + quoteIdent(referencedColumn(proto, rel.JoinReferences)) +
+		" WHERE " + join + "." + quoteIdent(rel.ForeignKey) + " IN (" + strings.Join(params, ", ") + ")"
+	if cond := softDeleteCondition(proto); cond != "" {
+		query += " AND " + alias + "." + cond
+	}
+	return "SELECT " + columnNames(proto) + ", crud_owner_key FROM (" + query + ") AS " + alias
+}
+
+// Assign related models to relation field
+func assignRelated(field reflect.Value, values []reflect.Value) {
+	t := field.Type()
+	if t.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(t, 0, len(values))
+		for _, value := range values {
+			if t.Elem().Kind() != reflect.Ptr {
+				value = value.Elem()
+			}
+			slice = reflect.Append(slice, value)
+		}
+		field.Set(slice)
+		return
+	}
+	if len(values) == 0 {
+		field.Set(reflect.Zero(t))
+		return
+	}
+	if t.Kind() == reflect.Ptr {
+		field.Set(values[0])
+		return
+	}
+	field.Set(values[0].Elem())
+}
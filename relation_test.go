@@ -0,0 +1,183 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Model related to books
+type author struct {
+	Id    int64
+	Name  string
+	Books []*book
+}
+
+func (m *author) Columns() ([]string, []interface{}) {
+	return []string{"name"}, []interface{}{&m.Name}
+}
+
+func (m *author) PrimaryKey() ([]string, []interface{}) {
+	return []string{"id"}, []interface{}{&m.Id}
+}
+
+func (m *author) Sequences() ([]string, []interface{}) {
+	return []string{"id"}, []interface{}{&m.Id}
+}
+
+func (m *author) TableName() string {
+	return "author"
+}
+
+func (m *author) Validate() error {
+	return nil
+}
+
+func (m *author) Relations() map[string]Relation {
+	return map[string]Relation{"Books": HasMany("author_id")}
+}
+
+// Model with optional author and tags
+type book struct {
+	Id       int64
+	AuthorId *int64
+	Author   *author
+	Tags     []tag
+}
+
+func (m *book) Columns() ([]string, []interface{}) {
+	return []string{"author_id"}, []interface{}{&m.AuthorId}
+}
+
+func (m *book) PrimaryKey() ([]string, []interface{}) {
+	return []string{"id"}, []interface{}{&m.Id}
+}
+
+func (m *book) Sequences() ([]string, []interface{}) {
+	return []string{"id"}, []interface{}{&m.Id}
+}
+
+func (m *book) TableName() string {
+	return "book"
+}
+
+func (m *book) Validate() error {
+	return nil
+}
+
+func (m *book) Relations() map[string]Relation {
+	return map[string]Relation{
+		"Author": BelongsTo("author_id"),
+		"Tags":   ManyToMany("book_tag", "book_id", "tag_code"),
+	}
+}
+
+func authorId(id int64) *int64 {
+	return &id
+}
+
+func TestPreloadBelongsTo(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{columns: []string{"id", "name"}, rows: [][]driver.Value{{int64(1), "x"}}})
+	books := []book{{Id: 1, AuthorId: authorId(1)}, {Id: 2}, {Id: 3, AuthorId: authorId(1)}, {Id: 4, AuthorId: authorId(2)}}
+	if err := Preload[book](db, books, "Author"); err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT "id", "name" FROM "author" WHERE ("id" IN ($1, $2))`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+	if !reflect.DeepEqual(db.args[0], []interface{}{int64(1), int64(2)}) {
+		t.Errorf("arguments %v", db.args[0])
+	}
+	if books[0].Author == nil || books[0].Author.Name != "x" || books[2].Author != books[0].Author {
+		t.Errorf("author of books 1 and 3 %+v %+v", books[0].Author, books[2].Author)
+	}
+	if books[1].Author != nil || books[3].Author != nil {
+		t.Errorf("author of books 2 and 4 %+v %+v", books[1].Author, books[3].Author)
+	}
+}
+
+func TestPreloadHasMany(t *testing.T) {
+	db := newTestDB(t)
+	rows := [][]driver.Value{{int64(1), int64(1)}, {int64(2), int64(1)}}
+	db.push(testResult{columns: []string{"id", "author_id"}, rows: rows})
+	authors := []author{{Id: 1, Books: []*book{{Id: 9}}}, {Id: 2, Books: []*book{{Id: 9}}}}
+	if err := Preload[author](db, authors, "Books"); err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT "id", "author_id" FROM "book" WHERE ("author_id" IN ($1, $2))`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+	if books := authors[0].Books; len(books) != 2 || books[0].Id != 1 || books[1].Id != 2 {
+		t.Errorf("books of author 1 %+v", books)
+	}
+	if books := authors[1].Books; books == nil || len(books) != 0 {
+		t.Errorf("books of author 2 %+v", books)
+	}
+}
+
+func TestPreloadManyToMany(t *testing.T) {
+	db := newTestDB(t)
+	rows := [][]driver.Value{{"a", "x", int64(1)}, {"b", "y", int64(1)}, {"a", "x", int64(2)}}
+	db.push(testResult{columns: []string{"code", "name", "crud_owner_key"}, rows: rows})
+	books := []book{{Id: 1}, {Id: 2}, {Id: 3}}
+	if err := Preload[book](db, books, "Tags"); err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT "code", "name", crud_owner_key FROM (SELECT "tag".*, "book_tag"."book_id" AS crud_owner_key FROM "tag" AS "tag" JOIN "book_tag" ON "book_tag"."tag_code" = "tag"."code" WHERE "book_tag"."book_id" IN ($1, $2, $3)) AS "tag"`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+	if tags := books[0].Tags; len(tags) != 2 || tags[0].Code != "a" || tags[1].Code != "b" {
+		t.Errorf("tags of book 1 %+v", tags)
+	}
+	if tags := books[1].Tags; len(tags) != 1 || tags[0].Name != "x" {
+		t.Errorf("tags of book 2 %+v", tags)
+	}
+	if tags := books[2].Tags; len(tags) != 0 {
+		t.Errorf("tags of book 3 %+v", tags)
+	}
+}
+
+func TestPreloadWithoutKeys(t *testing.T) {
+	db := newTestDB(t)
+	books := []book{{Id: 1, Author: &author{Id: 5}}, {Id: 2}}
+	if err := Preload[book](db, books, "Author"); err != nil {
+		t.Fatal(err)
+	}
+	if queries := db.executed(); len(queries) != 0 {
+		t.Errorf("executed %q", queries)
+	}
+	if books[0].Author != nil {
+		t.Errorf("author %+v is kept", books[0].Author)
+	}
+	if err := Preload[book](db, nil, "Author"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPreloadUnknownRelation(t *testing.T) {
+	db := newTestDB(t)
+	for _, name := range []string{"Publisher", "Id"} {
+		if err := Preload[book](db, []book{{Id: 1}}, name); !errors.Is(err, ErrUnknownRelation) {
+			t.Errorf("Preload(%s) = %v, want ErrUnknownRelation", name, err)
+		}
+	}
+}
+
+func TestPreloadChunksKeys(t *testing.T) {
+	db := newTestDB(t)
+	books := make([]book, maxQueryParams+1)
+	for key := range books {
+		books[key] = book{Id: int64(key), AuthorId: authorId(int64(key))}
+	}
+	if err := Preload[book](db, books, "Author"); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.args) != 2 || len(db.args[0]) != maxQueryParams || len(db.args[1]) != 1 {
+		t.Errorf("%d queries", len(db.args))
+	}
+}
@@ -0,0 +1,213 @@
+package crud
+
+import (
+	"context"
+	"strconv"
+)
+
+// Model with soft delete column, e.g. deleted_at
+type SoftDeleter interface {
+	SoftDeleteColumn() (name string, attributeLink interface{})
+}
+
+// Filter wrapper which includes soft deleted rows into search
+type unscopedFilter struct {
+	Filter
+}
+
+// Include soft deleted rows into search by filter
+func WithDeleted(filter Filter) Filter {
+	return unscopedFilter{filter}
+}
+
+// Filter without WithDeleted wrapper
+func unscope(filter Filter) Filter {
+	if unscoped, ok := filter.(unscopedFilter); ok {
+		return unscoped.Filter
+	}
+	return filter
+}
+
+// Apply WithDeleted wrapper of original filter to replacement
+func rescope(original Filter, filter Filter) Filter {
+	if _, ok := original.(unscopedFilter); ok {
+		return WithDeleted(filter)
+	}
+	return filter
+}
+
+// SQL condition excluding soft deleted rows
+func softDeleteCondition(m Cruder) string {
+	if sd, ok := m.(SoftDeleter); ok {
+		name, _ := sd.SoftDeleteColumn()
+		return quoteIdent(name) + " IS NULL"
+	}
+	return ""
+}
+
+// SQL search Query. Soft deleted rows are excluded unless filter is wrapped with WithDeleted
+func SearchQuery(m Cruder, filter Filter) string {
+	return selectQuery(m, columnNames(m), filter)
+}
+
+// SQL select Query of given select list from model table
+func selectQuery(m Cruder, list string, filter Filter) string {
+	source := quoteIdent(m.TableName())
+	unscoped, ok := filter.(unscopedFilter)
+	if ok {
+		filter = unscoped.Filter
+	} else if q, is := filter.(*Query); is {
+		sql, _ := q.render(softDeleteCondition(m))
+		return "SELECT " + list + " FROM " + source + " " + sql
+	} else if cond := softDeleteCondition(m); cond != "" {
+		all := "*"
+		if name, _, ok := systemVersion(m); ok {
+			all += ", " + name
+		}
+		source = "(SELECT " + all + " FROM " + source + " WHERE " + cond + ") AS " + quoteIdent(tableAlias(m))
+	}
+	query := "SELECT " + list + " FROM " + source
+	if filter != nil {
+		query += " " + filter.String()
+	}
+	return query
+}
+
+// Table name without schema
+func tableAlias(m Cruder) string {
+	table := m.TableName()
+	for i := len(table) - 1; i >= 0; i-- {
+		if table[i] == '.' {
+			return table[i+1:]
+		}
+	}
+	return table
+}
+
+// Load model including soft deleted
+func LoadWithDeleted(dbo DSLer, m Cruder) (find bool, err error) {
+	return LoadWithDeletedContext(context.Background(), WithContext(dbo), m)
+}
+
+// Load model including soft deleted with context
+func LoadWithDeletedContext(ctx context.Context, dbo DSLerContext, m Cruder) (find bool, err error) {
+	return load(ctx, dbo, m, getSelectQuery(m))
+}
+
+// SQL soft delete Query
+func getSoftDeleteQuery(d Dialect, m Cruder) string {
+	return statementsOf(d, m).softDelete
+}
+
+func buildSoftDeleteQuery(d Dialect, m Cruder, name string) string {
+	sql, count := getSqlPrimary(m, 0)
+	name = quoteIdent(name)
+	return "UPDATE " + quoteIdent(m.TableName()) + " SET " + name + " = $" + strconv.Itoa(count+1) + " WHERE " + sql + " AND " + name + " IS NULL" + returningColumn(d, name) + " ;"
+}
+
+// RETURNING clause of single column if dialect supports it
+func returningColumn(d Dialect, name string) string {
+	if !d.Returning() {
+		return ""
+	}
+	return " RETURNING " + name
+}
+
+func softDelete(ctx context.Context, dbo DSLerContext, m Cruder, sd SoftDeleter) (err error) {
+	_, link := sd.SoftDeleteColumn()
+	_, idlinks := m.PrimaryKey()
+	now := Clock()
+	args := append(idlinks, now)
+	d := dialectOf(dbo)
+	ctx = withOperation(ctx, OpDelete, m)
+	if !d.Returning() {
+		result, errExec := execContext(ctx, dbo, getSoftDeleteQuery(d, m), args...)
+		if errExec != nil {
+			return dbError(errExec)
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			err = setTime(link, now)
+		}
+		return
+	}
+	rows, err := queryContext(ctx, dbo, getSoftDeleteQuery(d, m), args...)
+	if err != nil {
+		err = dbError(err)
+		return
+	}
+	defer rows.Close()
+	if rows.Next() {
+		err = rows.Scan(link)
+		return
+	}
+	err = rows.Err()
+	return
+}
+
+// Delete model row permanently, even if it supports soft delete
+func HardDelete(dbo DSLer, m Cruder) error {
+	return HardDeleteContext(context.Background(), WithContext(dbo), m)
+}
+
+// Delete model row permanently with context
+func HardDeleteContext(ctx context.Context, dbo DSLerContext, m Cruder) error {
+	return deleteWithHooks(ctx, dbo, m, func(ctx context.Context) error {
+		return hardDelete(ctx, dbo, m)
+	})
+}
+
+func hardDelete(ctx context.Context, dbo DSLerContext, m Cruder) error {
+	_, idlinks := m.PrimaryKey()
+	_, err := execContext(withOperation(ctx, OpDelete, m), dbo, getDeleteQuery(m), idlinks...)
+	return dbError(err)
+}
+
+// SQL restore Query
+func getRestoreQuery(d Dialect, m Cruder) string {
+	return statementsOf(d, m).restore
+}
+
+func buildRestoreQuery(d Dialect, m Cruder, name string) string {
+	sql, _ := getSqlPrimary(m, 0)
+	name = quoteIdent(name)
+	return "UPDATE " + quoteIdent(m.TableName()) + " SET " + name + " = NULL WHERE " + sql + returningColumn(d, name) + " ;"
+}
+
+// Restore soft deleted model
+func Restore(dbo DSLer, m Cruder) error {
+	return RestoreContext(context.Background(), WithContext(dbo), m)
+}
+
+// Restore soft deleted model with context
+func RestoreContext(ctx context.Context, dbo DSLerContext, m Cruder) (err error) {
+	sd, ok := m.(SoftDeleter)
+	if !ok {
+		err = ErrNotSoftDeleter
+		return
+	}
+	if err = checkIdentifiers(m); err != nil {
+		return
+	}
+	_, link := sd.SoftDeleteColumn()
+	_, idlinks := m.PrimaryKey()
+	d := dialectOf(dbo)
+	ctx = withOperation(ctx, OpUpdate, m)
+	if !d.Returning() {
+		if _, err = execContext(ctx, dbo, getRestoreQuery(d, m), idlinks...); err != nil {
+			return dbError(err)
+		}
+		return setValue(link, nil)
+	}
+	rows, err := queryContext(ctx, dbo, getRestoreQuery(d, m), idlinks...)
+	if err != nil {
+		err = dbError(err)
+		return
+	}
+	defer rows.Close()
+	if rows.Next() {
+		err = rows.Scan(link)
+		return
+	}
+	err = rows.Err()
+	return
+}
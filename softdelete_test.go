@@ -0,0 +1,75 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSoftDeleteQueries(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	db.push(documentRow(1, 1, nil), documentRow(1, 1, nil), testResult{columns: []string{"deleted_at"}, rows: [][]driver.Value{{now}}})
+	m := &document{Id: 1}
+	if find, err := Load(db, m); err != nil || !find {
+		t.Fatalf("Load = %v, %v", find, err)
+	}
+	if find, err := LoadWithDeleted(db, m); err != nil || !find {
+		t.Fatalf("LoadWithDeleted = %v, %v", find, err)
+	}
+	if err := Delete(db, m); err != nil {
+		t.Fatal(err)
+	}
+	if m.DeletedAt == nil || !m.DeletedAt.Equal(now) {
+		t.Errorf("deleted at %v, want %v", m.DeletedAt, now)
+	}
+	if err := HardDelete(db, m); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(db, m); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`SELECT "id", "title", "version", "created_at", "updated_at", "deleted_at" FROM "document" WHERE "id" = $1 AND "deleted_at" IS NULL ;`,
+		`SELECT "id", "title", "version", "created_at", "updated_at", "deleted_at" FROM "document" WHERE "id" = $1 ;`,
+		`UPDATE "document" SET "deleted_at" = $2 WHERE "id" = $1 AND "deleted_at" IS NULL RETURNING "deleted_at" ;`,
+		`DELETE FROM "document" WHERE "id" = $1 ;`,
+		`UPDATE "document" SET "deleted_at" = NULL WHERE "id" = $1 RETURNING "deleted_at" ;`,
+	}
+	queries := db.executed()
+	if len(queries) != len(want) {
+		t.Fatalf("executed %q", queries)
+	}
+	for key := range want {
+		if queries[key] != want[key] {
+			t.Errorf("executed %s, want %s", queries[key], want[key])
+		}
+	}
+}
+
+func TestSaveKeepsDeletionTime(t *testing.T) {
+	db := newTestDB(t)
+	db.push(documentRow(1, 1, nil), documentRow(1, 2, nil))
+	m := &document{Id: 1}
+	if _, err := Load(db, m); err != nil {
+		t.Fatal(err)
+	}
+	m.Title = "b"
+	if err := Save(db, m); err != nil {
+		t.Fatal(err)
+	}
+	queries := db.executed()
+	if len(queries) != 2 || !strings.HasPrefix(queries[1], "UPDATE") || strings.Contains(queries[1], `"deleted_at" =`) {
+		t.Errorf("executed %q", queries)
+	}
+	if err := UpdateColumns(db, m, "deleted_at"); err != nil {
+		t.Errorf("UpdateColumns(deleted_at) = %v", err)
+	}
+	if len(db.executed()) != 2 {
+		t.Errorf("deletion time is updated: %q", db.executed())
+	}
+	if clause := upsertClause(m, nil); strings.Contains(strings.Join(clause.Update, ","), "deleted_at") {
+		t.Errorf("upsert assigns deletion time: %v", clause.Update)
+	}
+}
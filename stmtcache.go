@@ -0,0 +1,272 @@
+package crud
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+)
+
+// Prepared statements kept by StmtCache when size is not set
+const defaultStmtCacheSize = 256
+
+// Statement preparer, e.g. *sql.DB, *sql.Conn or *sql.Tx
+type Preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// DSLer wrapper which prepares statements lazily and reuses them.
+// Statements of least recently used queries are closed when cache is full.
+// Transactions started by WithTx get own cache reusing statements of parent
+type StmtCache struct {
+	ds       DSLer
+	preparer Preparer
+	tx       *sql.Tx    // transaction of statements, nil outside of transaction
+	parent   *StmtCache // cache of database statements derived into transaction
+	size     int
+	mu       sync.Mutex
+	stmts    map[string]*list.Element
+	lru      *list.List
+}
+
+type cachedStmt struct {
+	query   string
+	stmt    *sql.Stmt
+	users   int  // callers executing statement
+	evicted bool // removed from cache, closed by its last user
+}
+
+// Cache prepared statements of DSLer, size limits count of statements, zero means default.
+// Without Preparer under wrappers queries are executed directly
+func WithStmtCache(ds DSLer, size int) *StmtCache {
+	if size <= 0 {
+		size = defaultStmtCacheSize
+	}
+	inner, _ := unwrapDSLer(ds)
+	c := &StmtCache{ds: ds, size: size, stmts: map[string]*list.Element{}, lru: list.New()}
+	c.preparer, _ = inner.(Preparer)
+	c.tx = sqlTx(inner)
+	return c
+}
+
+func sqlTx(ds DSLer) *sql.Tx {
+	switch t := ds.(type) {
+	case *Tx:
+		return t.Tx
+	case *sql.Tx:
+		return t
+	}
+	return nil
+}
+
+func (c *StmtCache) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.QueryContext(context.Background(), query, args...)
+}
+
+func (c *StmtCache) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.QueryRowContext(context.Background(), query, args...)
+}
+
+func (c *StmtCache) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.ExecContext(context.Background(), query, args...)
+}
+
+func (c *StmtCache) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	if c.preparer == nil {
+		return WithContext(c.ds).QueryContext(ctx, query, args...)
+	}
+	for attempt := 0; ; attempt++ {
+		cs, errPrepare := c.stmt(ctx, query)
+		if errPrepare != nil {
+			return nil, errPrepare
+		}
+		rows, err = cs.stmt.QueryContext(ctx, args...)
+		c.release(cs)
+		if !c.retry(cs, err, attempt) {
+			return
+		}
+	}
+}
+
+func (c *StmtCache) QueryRowContext(ctx context.Context, query string, args ...interface{}) (row *sql.Row) {
+	if c.preparer == nil {
+		return WithContext(c.ds).QueryRowContext(ctx, query, args...)
+	}
+	for attempt := 0; ; attempt++ {
+		cs, err := c.stmt(ctx, query)
+		if err != nil {
+			// row can not be made with error, so unprepared query reports it
+			return WithContext(c.ds).QueryRowContext(ctx, query, args...)
+		}
+		row = cs.stmt.QueryRowContext(ctx, args...)
+		c.release(cs)
+		if !c.retry(cs, row.Err(), attempt) {
+			return
+		}
+	}
+}
+
+func (c *StmtCache) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	if c.preparer == nil {
+		return WithContext(c.ds).ExecContext(ctx, query, args...)
+	}
+	for attempt := 0; ; attempt++ {
+		cs, errPrepare := c.stmt(ctx, query)
+		if errPrepare != nil {
+			return nil, errPrepare
+		}
+		result, err = cs.stmt.ExecContext(ctx, args...)
+		c.release(cs)
+		if !c.retry(cs, err, attempt) {
+			return
+		}
+	}
+}
+
+// Close all cached statements, statements in use are closed when they are released
+func (c *StmtCache) Close() (err error) {
+	c.mu.Lock()
+	var unused []*sql.Stmt
+	for c.lru.Len() > 0 {
+		if stmt := c.evict(c.lru.Back()); stmt != nil {
+			unused = append(unused, stmt)
+		}
+	}
+	c.mu.Unlock()
+	for _, stmt := range unused {
+		if errClose := stmt.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}
+	return
+}
+
+func (c *StmtCache) Dialect() Dialect {
+	return dialectOf(c.ds)
+}
+
+func (c *StmtCache) unwrap() DSLer {
+	return c.ds
+}
+
+func (c *StmtCache) rewrap(inner DSLer) DSLer {
+	innermost, _ := unwrapDSLer(inner)
+	tx := sqlTx(innermost)
+	if tx != nil && tx == c.tx {
+		return c
+	}
+	child := WithStmtCache(inner, c.size)
+	if tx != nil && c.tx == nil && c.preparer != nil {
+		child.parent = c
+	}
+	return child
+}
+
+// Cached statement of query checked out until release, prepared on first use
+func (c *StmtCache) stmt(ctx context.Context, query string) (cs *cachedStmt, err error) {
+	if cs = c.checkout(query); cs != nil {
+		return
+	}
+	var stmt *sql.Stmt
+	// transaction holds its connection, so database statement is reused only if it is prepared already
+	if parent := c.parentStmt(query); parent != nil {
+		stmt = c.tx.StmtContext(ctx, parent.stmt)
+		c.parent.release(parent)
+	} else if stmt, err = c.preparer.PrepareContext(ctx, query); err != nil {
+		return nil, dbError(err)
+	}
+	var unused *sql.Stmt
+	c.mu.Lock()
+	if e, ok := c.stmts[query]; ok {
+		// statement was prepared concurrently
+		unused = stmt
+		cs = e.Value.(*cachedStmt)
+		cs.users++
+		c.lru.MoveToFront(e)
+	} else {
+		cs = &cachedStmt{query: query, stmt: stmt, users: 1}
+		c.stmts[query] = c.lru.PushFront(cs)
+		if c.lru.Len() > c.size {
+			unused = c.evict(c.lru.Back())
+		}
+	}
+	c.mu.Unlock()
+	if unused != nil {
+		unused.Close()
+	}
+	return
+}
+
+func (c *StmtCache) checkout(query string) *cachedStmt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.stmts[query]; ok {
+		c.lru.MoveToFront(e)
+		cs := e.Value.(*cachedStmt)
+		cs.users++
+		return cs
+	}
+	return nil
+}
+
+// Return checked out statement, the last user closes evicted one.
+// Rows of closed statement stay readable, database/sql finalizes statement after them
+func (c *StmtCache) release(cs *cachedStmt) {
+	c.mu.Lock()
+	cs.users--
+	unused := cs.evicted && cs.users == 0
+	c.mu.Unlock()
+	if unused {
+		cs.stmt.Close()
+	}
+}
+
+// Remove statement from cache under lock, returns statement to close if nobody uses it
+func (c *StmtCache) evict(e *list.Element) *sql.Stmt {
+	cs := c.lru.Remove(e).(*cachedStmt)
+	delete(c.stmts, cs.query)
+	cs.evicted = true
+	if cs.users > 0 {
+		return nil
+	}
+	return cs.stmt
+}
+
+func (c *StmtCache) parentStmt(query string) *cachedStmt {
+	if c.parent == nil {
+		return nil
+	}
+	return c.parent.checkout(query)
+}
+
+// Drop statement of query if it is still cached, nil stmt drops any statement of query
+func (c *StmtCache) invalidate(query string, stmt *sql.Stmt) {
+	var unused *sql.Stmt
+	c.mu.Lock()
+	if e, ok := c.stmts[query]; ok && (stmt == nil || e.Value.(*cachedStmt).stmt == stmt) {
+		unused = c.evict(e)
+	}
+	c.mu.Unlock()
+	if unused != nil {
+		unused.Close()
+	}
+	if c.parent != nil {
+		c.parent.invalidate(query, nil)
+	}
+}
+
+// Invalidate statement with stale plan and report if query should be retried.
+// Failed statement aborts transaction, so only queries outside of transaction are retried
+func (c *StmtCache) retry(cs *cachedStmt, err error, attempt int) bool {
+	if !stalePlan(err) {
+		return false
+	}
+	c.invalidate(cs.query, cs.stmt)
+	return attempt == 0 && c.tx == nil
+}
+
+// Postgres error of statement prepared before schema change
+func stalePlan(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "cached plan must not change result type")
+}
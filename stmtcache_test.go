@@ -0,0 +1,91 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestStmtCacheReusesStatements(t *testing.T) {
+	db := newTestDB(t)
+	c := WithStmtCache(db, 2)
+	defer c.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := c.Exec("SELECT 1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if db.prepared != 1 {
+		t.Errorf("prepared %d statements, want 1", db.prepared)
+	}
+}
+
+func TestStmtCacheKeepsCheckedOutStatement(t *testing.T) {
+	db := newTestDB(t)
+	c := WithStmtCache(db, 1)
+	defer c.Close()
+	cs, err := c.stmt(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// evicts statement of SELECT 1 while it is checked out
+	if _, err = c.Exec("SELECT 2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cs.stmt.Exec(); err != nil {
+		t.Fatalf("evicted statement in use: %v", err)
+	}
+	c.release(cs)
+	if _, err = cs.stmt.Exec(); err == nil {
+		t.Error("evicted statement is not closed after release")
+	}
+}
+
+func TestStmtCacheEvictionUnderConcurrentUse(t *testing.T) {
+	db := newTestDB(t)
+	c := WithStmtCache(db, 1)
+	var wg sync.WaitGroup
+	errs := make(chan error, 800)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				query := "SELECT " + strconv.Itoa((g+i)%5)
+				if _, err := c.Exec(query); err != nil {
+					errs <- err
+				}
+				rows, err := c.Query(query)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				rows.Close()
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStmtCacheRetriesStalePlan(t *testing.T) {
+	db := newTestDB(t)
+	c := WithStmtCache(db, 0)
+	defer c.Close()
+	db.push(testResult{err: errors.New("cached plan must not change result type")}, testResult{affected: 1})
+	result, err := c.Exec("UPDATE probe SET name = $1", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected, _ := result.RowsAffected(); affected != 1 || db.prepared != 2 {
+		t.Errorf("affected %d, prepared %d", affected, db.prepared)
+	}
+}
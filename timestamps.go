@@ -0,0 +1,76 @@
+package crud
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Model with creation and modification time columns, e.g. created_at and updated_at.
+// Empty name means the model has no such column
+type Timestamped interface {
+	CreatedAtColumn() (name string, attributeLink interface{})
+	UpdatedAtColumn() (name string, attributeLink interface{})
+}
+
+// Clock of timestamps set by crud, replace it for deterministic tests
+var Clock = time.Now
+
+func createdAtColumn(m Cruder) (name string, attributeLink interface{}, ok bool) {
+	if t, is := m.(Timestamped); is {
+		name, attributeLink = t.CreatedAtColumn()
+		ok = name != ""
+	}
+	return
+}
+
+func updatedAtColumn(m Cruder) (name string, attributeLink interface{}, ok bool) {
+	if t, is := m.(Timestamped); is {
+		name, attributeLink = t.UpdatedAtColumn()
+		ok = name != ""
+	}
+	return
+}
+
+// Set created_at and updated_at before insert
+func stampCreate(m Cruder) (err error) {
+	now := Clock()
+	if _, link, ok := createdAtColumn(m); ok {
+		if err = setTime(link, now); err != nil {
+			return
+		}
+	}
+	if _, link, ok := updatedAtColumn(m); ok {
+		err = setTime(link, now)
+	}
+	return
+}
+
+// Set updated_at before update and add it to updated columns
+func stampUpdate(m Cruder, columns []string) ([]string, error) {
+	name, link, ok := updatedAtColumn(m)
+	if !ok {
+		return columns, nil
+	}
+	if err := setTime(link, Clock()); err != nil {
+		return columns, err
+	}
+	if !existsInArrayString(name, columns) {
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+func setTime(link interface{}, t time.Time) error {
+	switch v := link.(type) {
+	case *time.Time:
+		*v = t
+	case **time.Time:
+		*v = &t
+	case *sql.NullTime:
+		*v = sql.NullTime{Time: t, Valid: true}
+	default:
+		return fmt.Errorf("unsupported timestamp type %T", link)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package crud
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fix Clock for test
+func useClock(t testing.TB, now time.Time) {
+	clock := Clock
+	Clock = func() time.Time { return now }
+	t.Cleanup(func() { Clock = clock })
+}
+
+func TestCreateSetsTimestamps(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useClock(t, now)
+	db := newTestDB(t)
+	db.push(documentRow(1, 1, nil))
+	m := &document{Title: "a"}
+	if err := Save(db, m); err != nil {
+		t.Fatal(err)
+	}
+	query := db.executed()[0]
+	if !strings.Contains(query, `"created_at","updated_at"`) {
+		t.Errorf("executed %s", query)
+	}
+	if args := db.args[0]; len(args) != 5 || args[2] != now || args[3] != now {
+		t.Errorf("arguments %v, want both timestamps %v", args, now)
+	}
+}
+
+func TestUpdateSetsUpdatedAt(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useClock(t, now)
+	db := newTestDB(t)
+	db.push(documentRow(1, 2, nil))
+	m := &document{Id: 1, Title: "a", Version: 1, CreatedAt: created, UpdatedAt: created}
+	if err := Save(db, m); err != nil {
+		t.Fatal(err)
+	}
+	query := db.executed()[0]
+	if strings.Contains(query, `"created_at" =`) || !strings.Contains(query, `"updated_at" = $3`) {
+		t.Errorf("executed %s", query)
+	}
+	if args := db.args[0]; len(args) != 4 || args[2] != now {
+		t.Errorf("arguments %v, want updated_at %v", args, now)
+	}
+}
+
+func TestSetTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var value time.Time
+	var link *time.Time
+	var null sql.NullTime
+	for _, target := range []interface{}{&value, &link, &null} {
+		if err := setTime(target, now); err != nil {
+			t.Errorf("setTime(%T) = %v", target, err)
+		}
+	}
+	if value != now || link == nil || *link != now || !null.Valid || null.Time != now {
+		t.Errorf("set %v, %v, %v", value, link, null)
+	}
+	var text string
+	if err := setTime(&text, now); err == nil {
+		t.Errorf("time is set to %T", &text)
+	}
+}
@@ -0,0 +1,129 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Transaction started by WithTx. Nested WithTx calls on it use savepoints
+type Tx struct {
+	*sql.Tx
+	savepoints int
+}
+
+// Starter of transactions, e.g. *sql.DB or *sql.Conn
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// WithTx options
+type TxOptions struct {
+	Tx *sql.TxOptions // isolation level and read only flag
+	// Retries of serialization failures and deadlocks, zero disables retry.
+	// Retried fn runs again on models changed by failed attempt, so it must be idempotent
+	Retries int
+	Backoff func(attempt int) time.Duration // delay before retry attempt starting from 1
+}
+
+// Exponential delay starting from 10ms
+func DefaultBackoff(attempt int) time.Duration {
+	return 10 * time.Millisecond << uint(attempt-1)
+}
+
+// Run fn in transaction: commit if it returns nil, roll back on error or panic.
+// Called with *Tx or *sql.Tx fn runs inside savepoint of that transaction.
+// Top level transaction is retried on serialization failure or deadlock when opts.Retries is set,
+// nil opts mean default transaction without retry
+func WithTx(ctx context.Context, db DSLer, opts *TxOptions, fn func(tx DSLer) error) (err error) {
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+	inner, wrap := unwrapDSLer(db)
+	wrapped := func(tx DSLer) error {
+		return fn(wrap(tx))
+	}
+	switch t := inner.(type) {
+	case *Tx:
+		return savepoint(ctx, t, wrapped)
+	case *sql.Tx:
+		return savepoint(ctx, &Tx{Tx: t}, wrapped)
+	}
+	beginner, ok := inner.(TxBeginner)
+	if !ok {
+		return errors.New("DSLer can not begin transaction")
+	}
+	backoff := opts.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		err = transaction(ctx, beginner, opts.Tx, wrapped)
+		if err == nil || !retryable(err) || attempt >= opts.Retries {
+			return
+		}
+		timer := time.NewTimer(backoff(attempt + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func transaction(ctx context.Context, beginner TxBeginner, opts *sql.TxOptions, fn func(tx DSLer) error) (err error) {
+	sqlTx, err := beginner.BeginTx(ctx, opts)
+	if err != nil {
+		return dbError(err)
+	}
+	tx := &Tx{Tx: sqlTx}
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicError(r)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = dbError(tx.Commit())
+	}()
+	err = fn(tx)
+	return
+}
+
+func savepoint(ctx context.Context, tx *Tx, fn func(tx DSLer) error) (err error) {
+	tx.savepoints++
+	name := "crud_savepoint_" + strconv.Itoa(tx.savepoints)
+	if _, err = tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return dbError(err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicError(r)
+		}
+		if err != nil {
+			tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			return
+		}
+		_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+		err = dbError(err)
+	}()
+	err = fn(tx)
+	return
+}
+
+func panicError(r interface{}) error {
+	if err, ok := r.(error); ok {
+		return fmt.Errorf("panic in transaction: %w", err)
+	}
+	return fmt.Errorf("panic in transaction: %v", r)
+}
+
+// Serialization failure or deadlock
+func retryable(err error) bool {
+	return errors.Is(err, ErrSerialization) || sqlStateErrors[SQLState(err)] == ErrSerialization
+}
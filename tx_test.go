@@ -0,0 +1,137 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithTx(t *testing.T) {
+	failure := errors.New("failure")
+	cases := []struct {
+		name string
+		fn   func(tx DSLer) error
+		err  string
+		want []string
+	}{
+		{"commit", func(tx DSLer) error {
+			_, err := tx.Exec("UPDATE t SET a = 1")
+			return err
+		}, "", []string{"BEGIN", "UPDATE t SET a = 1", "COMMIT"}},
+		{"rollback", func(tx DSLer) error {
+			return failure
+		}, "failure", []string{"BEGIN", "ROLLBACK"}},
+		{"panic", func(tx DSLer) error {
+			panic("boom")
+		}, "panic in transaction: boom", []string{"BEGIN", "ROLLBACK"}},
+	}
+	for _, c := range cases {
+		db := newTestDB(t)
+		err := WithTx(context.Background(), db, nil, c.fn)
+		if c.err == "" && err != nil || c.err != "" && (err == nil || err.Error() != c.err) {
+			t.Errorf("%s: WithTx = %v, want %s", c.name, err, c.err)
+		}
+		if queries := db.executed(); !reflect.DeepEqual(queries, c.want) {
+			t.Errorf("%s: executed %q, want %q", c.name, queries, c.want)
+		}
+	}
+}
+
+func TestWithTxSavepoints(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	err := WithTx(ctx, db, nil, func(tx DSLer) error {
+		inner := WithTx(ctx, tx, nil, func(tx DSLer) error {
+			return errors.New("failure")
+		})
+		if inner == nil {
+			t.Error("error of nested transaction is lost")
+		}
+		return WithTx(ctx, tx, nil, func(tx DSLer) error {
+			return WithTx(ctx, tx, nil, func(tx DSLer) error { return nil })
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"BEGIN",
+		"SAVEPOINT crud_savepoint_1",
+		"ROLLBACK TO SAVEPOINT crud_savepoint_1",
+		"SAVEPOINT crud_savepoint_2",
+		"SAVEPOINT crud_savepoint_3",
+		"RELEASE SAVEPOINT crud_savepoint_3",
+		"RELEASE SAVEPOINT crud_savepoint_2",
+		"COMMIT",
+	}
+	if queries := db.executed(); !reflect.DeepEqual(queries, want) {
+		t.Errorf("executed %q, want %q", queries, want)
+	}
+}
+
+func TestWithTxRetry(t *testing.T) {
+	serialization := testResult{err: testStateError("40001")}
+	cases := []struct {
+		name     string
+		opts     *TxOptions
+		results  []testResult
+		attempts int
+		delays   []int
+		err      error
+	}{
+		{"nil options do not retry", nil, []testResult{{}, serialization}, 1, nil, ErrSerialization},
+		{"retried until commit", &TxOptions{Retries: 3}, []testResult{{}, serialization, {}, serialization, {}, {}}, 3, []int{1, 2}, nil},
+		{"retries exhausted", &TxOptions{Retries: 1}, []testResult{{}, serialization, {}, serialization}, 2, []int{1}, ErrSerialization},
+		{"other error is not retried", &TxOptions{Retries: 3}, []testResult{{}, {err: testStateError("23505")}}, 1, nil, ErrUniqueViolation},
+	}
+	for _, c := range cases {
+		db := newTestDB(t)
+		db.push(c.results...)
+		var delays []int
+		if c.opts != nil {
+			c.opts.Backoff = func(attempt int) time.Duration {
+				delays = append(delays, attempt)
+				return 0
+			}
+		}
+		attempts := 0
+		err := WithTx(context.Background(), db, c.opts, func(tx DSLer) error {
+			attempts++
+			return nil
+		})
+		if c.err == nil && err != nil || c.err != nil && !errors.Is(err, c.err) {
+			t.Errorf("%s: WithTx = %v, want %v", c.name, err, c.err)
+		}
+		if attempts != c.attempts || !reflect.DeepEqual(delays, c.delays) {
+			t.Errorf("%s: %d attempts with delays %v, want %d with %v", c.name, attempts, delays, c.attempts, c.delays)
+		}
+	}
+}
+
+func TestWithTxRetryStopsOnCancel(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{}, testResult{err: testStateError("40P01")})
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &TxOptions{Retries: 3, Backoff: func(int) time.Duration {
+		cancel()
+		return time.Hour
+	}}
+	err := WithTx(ctx, db, opts, func(tx DSLer) error { return nil })
+	if !errors.Is(err, ErrSerialization) {
+		t.Errorf("WithTx = %v, want ErrSerialization", err)
+	}
+	if queries := strings.Join(db.executed(), ", "); queries != "BEGIN, COMMIT" {
+		t.Errorf("executed %s", queries)
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 4: 80 * time.Millisecond} {
+		if got := DefaultBackoff(attempt); got != want {
+			t.Errorf("DefaultBackoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Outcome of upsert
+type UpsertResult int
+
+const (
+	// conflicting row was kept: DO NOTHING or update condition was false
+	UpsertSkipped UpsertResult = iota
+	UpsertInserted
+	UpsertUpdated
+)
+
+func (r UpsertResult) String() string {
+	switch r {
+	case UpsertInserted:
+		return "inserted"
+	case UpsertUpdated:
+		return "updated"
+	}
+	return "skipped"
+}
+
+// Upsert options, zero value upserts on primary key assigning all updatable columns
+type UpsertOptions struct {
+	Conflict   []string // conflict target columns, primary key by default
+	Constraint string   // conflict target unique constraint, Postgres only, used instead of Conflict
+	Update     []string // assigned columns, all updatable columns by default
+	Exclude    []string // columns kept on update, e.g. created_at
+	DoNothing  bool     // keep conflicting row unchanged
+	// Condition of update with ? placeholders. Existing row is referenced by table name,
+	// inserted one by EXCLUDED (VALUES() in MySQL)
+	Where string
+	Args  []interface{} // arguments of Where
+}
+
+// Column is assigned on conflict
+func (o *UpsertOptions) updates(name string) bool {
+	if o == nil {
+		return true
+	}
+	if len(o.Update) > 0 && !existsInArrayString(name, o.Update) {
+		return false
+	}
+	return !existsInArrayString(name, o.Exclude)
+}
+
+func (o *UpsertOptions) check() (err error) {
+	names := append(append(append([]string{}, o.Conflict...), o.Update...), o.Exclude...)
+	if o.Constraint != "" {
+		names = append(names, o.Constraint)
+	}
+	for _, name := range names {
+		if err = checkIdentifier(name); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Insert model or update conflicting row according to options.
+// Postgres tells inserted row from updated by xmax, MySQL by affected rows count,
+// other dialects report every written row as inserted.
+// Without RETURNING the row is read back by primary key
+func UpsertWith(ds DSLer, m Cruder, opts UpsertOptions) (result UpsertResult, err error) {
+	return UpsertWithContext(context.Background(), WithContext(ds), m, opts)
+}
+
+// Upsert with options and context
+func UpsertWithContext(ctx context.Context, ds DSLerContext, m Cruder, opts UpsertOptions) (result UpsertResult, err error) {
+	err = instrument(ctx, OpUpsert, m, func(ctx context.Context) (err error) {
+		result, err = upsertWith(ctx, ds, m, &opts)
+		return
+	})
+	return
+}
+
+func upsertWith(ctx context.Context, ds DSLerContext, m Cruder, opts *UpsertOptions) (result UpsertResult, err error) {
+	if err = opts.check(); err != nil {
+		return
+	}
+	if err = beforeSave(ctx, ds, m, true); err != nil {
+		return
+	}
+	d := dialectOf(ds)
+	f := fieldsOf(m)
+	keyed := f.keyed()
+	query := buildInsertOnConflictQuery(d, m, keyed, opts)
+	_, insertions := f.upsertion(keyed)
+	if _, link, ok := versionColumn(m); ok {
+		insertions = append(insertions, link)
+	}
+	insertions = append(insertions, opts.Args...)
+	// version mismatch is the only reason to skip update
+	_, _, versioned := versionColumn(m)
+	stale := versioned && !upsertClause(m, opts).DoNothing && opts.Where == ""
+	qctx := withOperation(ctx, OpUpsert, m)
+	if d.Returning() {
+		result, err = upsertReturning(qctx, ds, d, f, query, insertions)
+	} else {
+		result, err = upsertExec(qctx, ds, f, query, insertions)
+	}
+	if err == nil && result == UpsertSkipped && stale {
+		err = ErrStaleObject
+	}
+	if err == nil && result != UpsertSkipped {
+		err = saved(ctx, ds, f)
+	}
+	return
+}
+
+func upsertReturning(ctx context.Context, ds DSLerContext, d Dialect, f *fields, query string, insertions []interface{}) (result UpsertResult, err error) {
+	inserted := true
+	dest := f.scans()
+	if d == Postgres {
+		dest = append(dest, &inserted)
+	}
+	err = queryRowContext(ctx, ds, query, insertions...).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return UpsertSkipped, nil
+	}
+	if err != nil {
+		return result, dbError(err)
+	}
+	if inserted {
+		return UpsertInserted, nil
+	}
+	return UpsertUpdated, nil
+}
+
+// MySQL reports one affected row for insert, two for update and none for unchanged row.
+// Updated row is read back by sequence taken from LAST_INSERT_ID
+func upsertExec(ctx context.Context, ds DSLerContext, f *fields, query string, insertions []interface{}) (result UpsertResult, err error) {
+	res, err := execContext(ctx, ds, query, insertions...)
+	if err != nil {
+		return result, dbError(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	switch {
+	case count == 0:
+		return UpsertSkipped, nil
+	case count == 1:
+		result = UpsertInserted
+	default:
+		result = UpsertUpdated
+	}
+	// LastInsertId is the sequence of updated row as well
+	err = reload(ctx, ds, f, res, true)
+	return
+}
@@ -0,0 +1,68 @@
+package crud
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildInsertOnConflictQuery(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		model   Cruder
+		opts    *UpsertOptions
+		want    string
+	}{
+		{Postgres, &tag{}, nil,
+			`INSERT INTO "tag" ("code","name") VALUES ( $1, $2) ON CONFLICT ("code") DO UPDATE SET "code" = EXCLUDED."code", "name" = EXCLUDED."name" RETURNING "code", "name" ;`},
+		{Postgres, &revision{}, nil,
+			`INSERT INTO "revision" ("code","version") VALUES ( $1, $2) ON CONFLICT ("code") DO UPDATE SET "code" = EXCLUDED."code", "version" = "revision"."version" + 1 WHERE "revision"."version" = $3 RETURNING "code", "version" ;`},
+		{Postgres, &tag{}, &UpsertOptions{DoNothing: true},
+			`INSERT INTO "tag" ("code","name") VALUES ( $1, $2) ON CONFLICT ("code") DO NOTHING RETURNING "code", "name", (xmax = 0) ;`},
+		{Postgres, &tag{}, &UpsertOptions{Update: []string{"name"}, Where: "tag.name <> ?"},
+			`INSERT INTO "tag" ("code","name") VALUES ( $1, $2) ON CONFLICT ("code") DO UPDATE SET "name" = EXCLUDED."name" WHERE (tag.name <> $3) RETURNING "code", "name", (xmax = 0) ;`},
+		{MySQL, &tag{}, &UpsertOptions{Exclude: []string{"code"}},
+			`INSERT INTO "tag" ("code","name") VALUES ( $1, $2) ON DUPLICATE KEY UPDATE "name" = VALUES("name") ;`},
+	}
+	for _, c := range cases {
+		if got := oneLine(buildInsertOnConflictQuery(c.dialect, c.model, false, c.opts)); got != c.want {
+			t.Errorf("%T upsert of %T = %s, want %s", c.dialect, c.model, got, c.want)
+		}
+	}
+}
+
+func TestUpsertOptionsCheck(t *testing.T) {
+	if err := (&UpsertOptions{Conflict: []string{"code"}, Exclude: []string{"created_at"}}).check(); err != nil {
+		t.Error(err)
+	}
+	if err := (&UpsertOptions{Update: []string{`name"; --`}}).check(); err == nil {
+		t.Error("invalid identifier is accepted")
+	}
+}
+
+func TestMySQLUpsertReloadsUpdatedRow(t *testing.T) {
+	for _, opts := range []*UpsertOptions{{Conflict: []string{"name"}}, nil} {
+		db := newTestDB(t)
+		db.push(testResult{affected: 2, lastId: 9}, probeRow(9))
+		m := &probe{Name: "a"}
+		var err error
+		if opts != nil {
+			var result UpsertResult
+			result, err = UpsertWith(WithDialect(db, MySQL), m, *opts)
+			if result != UpsertUpdated {
+				t.Errorf("result %s, want updated", result)
+			}
+		} else {
+			_, err = Upsert(WithDialect(db, MySQL), m)
+		}
+		if err != nil || m.Id != 9 {
+			t.Fatalf("upsert = %v, id %d, want 9", err, m.Id)
+		}
+		queries := db.executed()
+		if len(queries) != 2 || !strings.HasSuffix(queries[0], "`name` = VALUES(`name`), `order` = VALUES(`order`), `id` = LAST_INSERT_ID(`id`) ;") {
+			t.Errorf("executed %q", queries)
+		}
+		if args := db.args[1]; len(args) != 1 || args[0] != int64(9) {
+			t.Errorf("reloaded by %v", args)
+		}
+	}
+}
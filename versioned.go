@@ -0,0 +1,58 @@
+package crud
+
+import (
+	"database/sql"
+)
+
+// Model with optimistic lock column, e.g. version, lock_version or system xmin
+type Versioned interface {
+	VersionColumn() (name string, attributeLink interface{})
+}
+
+// Postgres system column of row version
+const xminColumn = "xmin"
+
+func versionColumn(m Cruder) (name string, attributeLink interface{}, ok bool) {
+	v, ok := m.(Versioned)
+	if !ok {
+		return
+	}
+	name, attributeLink = v.VersionColumn()
+	return
+}
+
+// SQL expression reading version column
+func versionExpr(name string) string {
+	if name == xminColumn {
+		return "xmin::text::bigint"
+	}
+	return quoteIdent(name)
+}
+
+// System version column is not a part of model columns and is read separately
+func systemVersion(m Cruder) (name string, attributeLink interface{}, ok bool) {
+	name, attributeLink, ok = versionColumn(m)
+	ok = ok && name == xminColumn
+	return
+}
+
+// SQL assignment incrementing version column, empty for system column
+func versionIncrement(m Cruder, table string) string {
+	name, _, ok := versionColumn(m)
+	if !ok || name == xminColumn {
+		return ""
+	}
+	name = quoteIdent(name)
+	if table != "" {
+		return name + " = " + quoteIdent(table) + "." + name + " + 1"
+	}
+	return name + " = " + name + " + 1"
+}
+
+// Versioned model update matching no rows means the row is stale
+func staleObject(m Cruder, err error) error {
+	if _, _, ok := versionColumn(m); ok && err == sql.ErrNoRows {
+		return ErrStaleObject
+	}
+	return err
+}
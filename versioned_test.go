@@ -0,0 +1,98 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// Model versioned by system column xmin
+type xminProbe struct {
+	probe
+	Xmin int64
+}
+
+func (m *xminProbe) VersionColumn() (string, interface{}) {
+	return "xmin", &m.Xmin
+}
+
+func TestSaveIncrementsVersion(t *testing.T) {
+	db := newTestDB(t)
+	db.push(documentRow(1, 4, nil))
+	m := &document{Id: 1, Title: "a", Version: 3}
+	if err := Save(db, m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != 4 {
+		t.Errorf("version %d, want 4", m.Version)
+	}
+	want := `UPDATE "document" SET "title" = $2, "updated_at" = $3, "version" = "version" + 1 WHERE "id" = $1 AND "version" = $4 RETURNING "id", "title", "version", "created_at", "updated_at", "deleted_at";`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+	if args := db.args[0]; len(args) != 4 || args[3] != int64(3) {
+		t.Errorf("arguments %v, want version 3 last", args)
+	}
+}
+
+func TestSaveStaleObject(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{columns: documentRow(1, 4, nil).columns})
+	m := &document{Id: 1, Title: "a", Version: 3}
+	if err := Save(db, m); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("error %v, want ErrStaleObject", err)
+	}
+	if m.Version != 3 {
+		t.Errorf("version %d, want 3", m.Version)
+	}
+}
+
+func TestSaveStaleObjectWithoutReturning(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{affected: 0})
+	m := &document{Id: 1, Title: "a", Version: 3}
+	if err := Save(WithDialect(db, MySQL), m); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("error %v, want ErrStaleObject", err)
+	}
+	if queries := db.executed(); len(queries) != 1 {
+		t.Errorf("executed %q", queries)
+	}
+}
+
+func TestSaveVersionedByXmin(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{
+		columns: []string{"id", "name", "order", "xmin"},
+		rows:    [][]driver.Value{{int64(5), "a", int64(1), int64(901)}},
+	})
+	m := &xminProbe{probe: probe{Id: 5, Name: "a", Order: 1}, Xmin: 900}
+	if err := Save(db, m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Xmin != 901 {
+		t.Errorf("xmin %d, want 901", m.Xmin)
+	}
+	want := `UPDATE "public"."probe" SET "name" = $2, "order" = $3 WHERE "id" = $1 AND xmin::text::bigint = $4 RETURNING "id", "name", "order", xmin::text::bigint;`
+	if queries := db.executed(); len(queries) != 1 || queries[0] != want {
+		t.Errorf("executed %q, want %s", queries, want)
+	}
+	db.push(testResult{columns: []string{"id", "name", "order", "xmin"}})
+	if err := Save(db, m); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("error %v, want ErrStaleObject", err)
+	}
+}
+
+func TestLoadReadsXmin(t *testing.T) {
+	db := newTestDB(t)
+	db.push(testResult{
+		columns: []string{"id", "name", "order", "xmin"},
+		rows:    [][]driver.Value{{int64(5), "a", int64(1), int64(77)}},
+	})
+	m := &xminProbe{probe: probe{Id: 5}}
+	if ok, err := Load(db, m); !ok || err != nil {
+		t.Fatalf("Load = %v, %v", ok, err)
+	}
+	if m.Xmin != 77 {
+		t.Errorf("xmin %d, want 77", m.Xmin)
+	}
+}